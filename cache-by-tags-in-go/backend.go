@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by a Backend when a key is not cached.
+var ErrNotFound = errors.New("cache: key not found")
+
+// TagError reports the tags for which an operation failed while it succeeded for the others.
+type TagError struct {
+	Op   string           // "set" or "invalidate"
+	Tags map[string]error // failed tags and the reason each failed
+}
+
+func (e *TagError) Error() string {
+	tags := e.failed()
+	msgs := make([]string, len(tags))
+	for i, tag := range tags {
+		msgs[i] = fmt.Sprintf("%s: %v", tag, e.Tags[tag])
+	}
+	return fmt.Sprintf("cache: %s failed for %d tags: %s", e.Op, len(tags), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the error of the first failed tag, in sorted order,
+// so that errors.Is can match causes such as context.DeadlineExceeded.
+func (e *TagError) Unwrap() error {
+	if tags := e.failed(); len(tags) > 0 {
+		return e.Tags[tags[0]]
+	}
+	return nil
+}
+
+// failed returns the failed tags, sorted.
+func (e *TagError) failed() []string {
+	tags := make([]string, 0, len(e.Tags))
+	for tag := range e.Tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Entry is a value to store with SetManyByTags.
+type Entry struct {
+	Key    string
+	Value  string
+	Expiry time.Duration
+	Tags   []string
+}
+
+// Backend stores cached values along with the tag sets that index them.
+type Backend interface {
+	// Get returns the value stored for key, or ErrNotFound.
+	Get(ctx context.Context, key string) (string, error)
+
+	// SetByTags stores value under key for expiry and adds key to the set of every tag.
+	// A *TagError lists the tags that key could not be added to.
+	SetByTags(ctx context.Context, key, value string, expiry time.Duration, tags []string) error
+
+	// SetManyByTags stores every entry like SetByTags, without making the batch atomic.
+	// It returns one error per entry, nil for the entries that were stored.
+	SetManyByTags(ctx context.Context, entries []Entry) []error
+
+	// Invalidate removes the given tag sets along with every key they hold,
+	// returning the keys that were held. A *TagError lists the tags that could
+	// not be invalidated, in which case the keys of the other tags are returned.
+	Invalidate(ctx context.Context, tags []string) ([]string, error)
+
+	// Tags returns the known tags matching glob.
+	Tags(ctx context.Context, glob string) ([]string, error)
+
+	// Keys returns the keys held by tag.
+	Keys(ctx context.Context, tag string) ([]string, error)
+
+	// KeyTags returns the tags key was stored with.
+	KeyTags(ctx context.Context, key string) ([]string, error)
+}
+
+// broadcaster is implemented by backends that can share invalidations
+// between the processes using them.
+type broadcaster interface {
+	// Publish announces keys that must be evicted from local tiers.
+	Publish(ctx context.Context, keys []string) error
+
+	// Subscribe returns the keys announced by Publish until close is called.
+	Subscribe(ctx context.Context) (keys <-chan []string, close func() error)
+}
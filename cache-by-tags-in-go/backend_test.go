@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// ctx is the context used throughout the tests.
+var ctx = context.Background()
+
+// backends returns every Backend implementation, each with empty storage.
+func backends(t *testing.T) map[string]Backend {
+	t.Helper()
+
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	// miniredis serves every slot, so the client fails multi-key commands itself
+	// where a real cluster would answer CROSSSLOT.
+	clusterSrv := miniredis.RunT(t)
+	clusterClient := redis.NewClusterClient(&redis.ClusterOptions{Addrs: []string{clusterSrv.Addr()}})
+	clusterClient.AddHook(slotCheckHook{t: t})
+	t.Cleanup(func() { clusterClient.Close() })
+
+	return map[string]Backend{
+		"memory":        newMemoryBackend(),
+		"redis":         newRedisBackend(client),
+		"redis-cluster": newRedisClusterBackend(clusterClient),
+	}
+}
+
+// slotCheckHook fails the test, and the command, when a command sent to the
+// cluster has keys in several slots. On a cluster, go-redis splits pipelines and
+// MULTI blocks by slot, so each of their commands is checked on its own.
+type slotCheckHook struct {
+	t *testing.T
+}
+
+func (h slotCheckHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, h.check(cmd)
+}
+
+func (h slotCheckHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h slotCheckHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	for _, cmd := range cmds {
+		if err := h.check(cmd); err != nil {
+			return ctx, err
+		}
+	}
+	return ctx, nil
+}
+
+func (h slotCheckHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+// check returns a CROSSSLOT error when the keys of cmd map to several slots.
+func (h slotCheckHook) check(cmd redis.Cmder) error {
+	keys := commandKeys(cmd.Args())
+	for _, key := range keys {
+		if keySlot(key) != keySlot(keys[0]) {
+			h.t.Errorf("%s sent keys in several slots: %v", cmd.Name(), keys)
+			err := errors.New("CROSSSLOT Keys in request don't hash to the same slot")
+			cmd.SetErr(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// commandKeys returns the keys of the multi-key commands used by the backends.
+func commandKeys(args []interface{}) []string {
+	if len(args) == 0 {
+		return nil
+	}
+
+	var keys []interface{}
+	switch strings.ToLower(fmt.Sprint(args[0])) {
+	case "del", "unlink", "exists", "mget", "sinter", "sunion", "sdiff", "rename":
+		keys = args[1:]
+	case "eval", "evalsha":
+		if len(args) > 2 {
+			if n, err := strconv.Atoi(fmt.Sprint(args[2])); err == nil && len(args) >= 3+n {
+				keys = args[3 : 3+n]
+			}
+		}
+	}
+
+	out := make([]string, len(keys))
+	for i, key := range keys {
+		out[i] = fmt.Sprint(key)
+	}
+	return out
+}
+
+// TestBackendConformance checks that every backend follows the same SetByTags
+// and Invalidate semantics.
+func TestBackendConformance(t *testing.T) {
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			mustSet(t, b, "data:key1", "v1", []string{"post1", "post2"})
+			mustSet(t, b, "data:key2", "v2", []string{"post2", "post3"})
+			mustSet(t, b, "data:key3", "v3", []string{"post3"})
+
+			if v, err := b.Get(ctx, "data:key1"); err != nil || v != "v1" {
+				t.Fatalf("Get(data:key1) = %q, %v, want %q", v, err, "v1")
+			}
+			if _, err := b.Get(ctx, "data:missing"); err != ErrNotFound {
+				t.Fatalf("Get(data:missing) error = %v, want ErrNotFound", err)
+			}
+			if keys, _ := b.Keys(ctx, "post2"); !equal(unique(keys), []string{"data:key1", "data:key2"}) {
+				t.Errorf("Keys(post2) = %v, want [data:key1 data:key2]", keys)
+			}
+			if tags, _ := b.KeyTags(ctx, "data:key2"); !equal(unique(tags), []string{"post2", "post3"}) {
+				t.Errorf("KeyTags(data:key2) = %v, want [post2 post3]", tags)
+			}
+
+			keys, err := b.Invalidate(ctx, []string{"post1", "post2"})
+			if err != nil {
+				t.Fatalf("Invalidate: %v", err)
+			}
+			if got, want := unique(keys), []string{"data:key1", "data:key2"}; !equal(got, want) {
+				t.Errorf("Invalidate returned %v, want %v", got, want)
+			}
+
+			var tests = []struct {
+				key  string
+				want error
+			}{
+				{key: "data:key1", want: ErrNotFound},
+				{key: "data:key2", want: ErrNotFound},
+				{key: "data:key3", want: nil},
+			}
+			for _, td := range tests {
+				if _, err := b.Get(ctx, td.key); err != td.want {
+					t.Errorf("Get(%s) error = %v, want %v", td.key, err, td.want)
+				}
+			}
+			if tags, _ := b.KeyTags(ctx, "data:key1"); len(tags) != 0 {
+				t.Errorf("KeyTags of an invalidated key = %v, want none", tags)
+			}
+
+			keys, err = b.Invalidate(ctx, []string{"post1"})
+			if err != nil || len(keys) != 0 {
+				t.Errorf("Invalidate of an emptied tag = %v, %v, want no keys", keys, err)
+			}
+		})
+	}
+}
+
+// TestSetManyByTags checks that a batch larger than a pipeline chunk is stored
+// and indexed like individual SetByTags calls.
+func TestSetManyByTags(t *testing.T) {
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			entries := make([]Entry, 0, 250)
+			for i := 0; i < 250; i++ {
+				entries = append(entries, Entry{
+					Key:    fmt.Sprintf("data:key%d", i),
+					Value:  fmt.Sprintf("v%d", i),
+					Expiry: 30 * time.Minute,
+					Tags:   []string{"all", fmt.Sprintf("mod%d", i%3)},
+				})
+			}
+
+			for i, err := range b.SetManyByTags(ctx, entries) {
+				if err != nil {
+					t.Fatalf("entry %d: %v", i, err)
+				}
+			}
+			if v, err := b.Get(ctx, "data:key249"); err != nil || v != "v249" {
+				t.Errorf("Get(data:key249) = %q, %v, want %q", v, err, "v249")
+			}
+			assertTagKeys(t, b, "all", 250)
+			assertTagKeys(t, b, "mod1", 83)
+			if tags, _ := b.KeyTags(ctx, "data:key4"); !equal(unique(tags), []string{"all", "mod1"}) {
+				t.Errorf("KeyTags(data:key4) = %v, want [all mod1]", tags)
+			}
+		})
+	}
+}
+
+// TestSetManyByTagsPartialFailure checks that only the entries of a failing tag are reported.
+func TestSetManyByTagsPartialFailure(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	defer client.Close()
+
+	b := newRedisBackend(client)
+	// a tag set holding the wrong type makes adding to post2 fail.
+	srv.Set(flatLayout("post2"), "not a set")
+
+	errs := b.SetManyByTags(ctx, []Entry{
+		{Key: "data:key1", Value: "v1", Tags: []string{"post1"}},
+		{Key: "data:key2", Value: "v2", Tags: []string{"post1", "post2"}},
+	})
+	if errs[0] != nil {
+		t.Errorf("entry 0: %v, want no error", errs[0])
+	}
+	tagErr, ok := errs[1].(*TagError)
+	if !ok {
+		t.Fatalf("entry 1: %v, want a *TagError", errs[1])
+	}
+	if _, failed := tagErr.Tags["post2"]; !failed || len(tagErr.Tags) != 1 {
+		t.Errorf("failed tags = %v, want post2 only", tagErr.Tags)
+	}
+}
+
+// TestInvalidatePartialFailure checks that a tag failing to invalidate is
+// reported while the other tags are still invalidated.
+func TestInvalidatePartialFailure(t *testing.T) {
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	defer client.Close()
+
+	b := newRedisBackend(client)
+	mustSet(t, b, "data:key1", "v1", []string{"post1"})
+	// a tag set holding the wrong type makes SMEMBERS fail for post2 only.
+	srv.Set(flatLayout("post2"), "not a set")
+
+	keys, err := b.Invalidate(ctx, []string{"post1", "post2"})
+	tagErr, ok := err.(*TagError)
+	if !ok {
+		t.Fatalf("Invalidate error = %v, want a *TagError", err)
+	}
+	if _, failed := tagErr.Tags["post2"]; !failed || len(tagErr.Tags) != 1 {
+		t.Errorf("failed tags = %v, want post2 only", tagErr.Tags)
+	}
+	if !equal(keys, []string{"data:key1"}) {
+		t.Errorf("Invalidate returned %v, want [data:key1]", keys)
+	}
+	if _, err := b.Get(ctx, "data:key1"); err != ErrNotFound {
+		t.Errorf("Get(data:key1) error = %v, want ErrNotFound", err)
+	}
+}
+
+// TestCanceledContext checks that no backend proceeds once its context is done.
+func TestCanceledContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			c := newCache(b)
+			if err := c.SetByTags(canceled, "data:key1", "v1", 0, []string{"post1"}); !errors.Is(err, context.Canceled) {
+				t.Errorf("SetByTags error = %v, want context.Canceled", err)
+			}
+			if err := c.Invalidate(canceled, []string{"post1"}); !errors.Is(err, context.Canceled) {
+				t.Errorf("Invalidate error = %v, want context.Canceled", err)
+			}
+		})
+	}
+}
+
+// TestBackendExpiry checks that values are not returned past their expiry.
+func TestBackendExpiry(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	b := newMemoryBackend()
+	b.now = clock.Now
+	mustSetExpiry(t, b, "data:key1", "v1", time.Minute, nil)
+	clock.Advance(time.Minute)
+
+	if _, err := b.Get(ctx, "data:key1"); err != ErrNotFound {
+		t.Errorf("Get of an expired key error = %v, want ErrNotFound", err)
+	}
+}
+
+func mustSet(t *testing.T, b Backend, key, value string, tags []string) {
+	t.Helper()
+	mustSetExpiry(t, b, key, value, 30*time.Minute, tags)
+}
+
+func mustSetExpiry(t *testing.T, b Backend, key, value string, expiry time.Duration, tags []string) {
+	t.Helper()
+	if err := b.SetByTags(ctx, key, value, expiry, tags); err != nil {
+		t.Fatalf("SetByTags(%s): %v", key, err)
+	}
+}
+
+func unique(keys []string) []string {
+	seen := make(map[string]bool)
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if !seen[k] {
+			seen[k] = true
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// tagPrefix is prepended to a tag to build the key of its redis set.
+	tagPrefix = "comment_by_tags:"
+
+	// invalidationChannel is the redis channel on which evicted keys are published.
+	invalidationChannel = "comment_by_tags:invalidate"
+)
+
+type cache struct {
+	backend       Backend
+	local         *lru
+	closeFn       func() error
+	stats         stats
+	compressAbove int
+	maxValueSize  int
+}
+
+// Option configures the cache.
+type Option func(*cache)
+
+// WithLocalCache puts an in-process LRU of the given size in front of the backend.
+// Entries are kept locally for at most ttl, which bounds staleness should an
+// invalidation message be missed.
+func WithLocalCache(size int, ttl time.Duration) Option {
+	return func(c *cache) {
+		c.local = newLRU(size, ttl)
+	}
+}
+
+// newCache returns a cache storing its data in the given backend.
+// When a local tier is enabled and the backend can broadcast, the cache subscribes
+// to invalidations so that keys invalidated by any instance are evicted locally.
+func newCache(backend Backend, opts ...Option) *cache {
+	c := &cache{backend: backend}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if b, ok := backend.(broadcaster); ok && c.local != nil {
+		var keys <-chan []string
+		keys, c.closeFn = b.Subscribe(context.Background())
+		go c.listen(keys)
+	}
+
+	return c
+}
+
+// Close stops listening for invalidation messages.
+func (c *cache) Close() error {
+	if c.closeFn == nil {
+		return nil
+	}
+	return c.closeFn()
+}
+
+// Get returns the cached value for key, checking the local tier before the backend.
+func (c *cache) Get(ctx context.Context, key string) (string, error) {
+	defer c.stats.getLatency.Observe(time.Now())
+
+	// gen tells whether an invalidation arrived while reading from the backend.
+	var gen uint64
+	if c.local != nil {
+		if v, ok := c.local.Get(key); ok {
+			c.stats.hits.Add(1)
+			c.stats.localHits.Add(1)
+			return v, nil
+		}
+		gen = c.local.Generation()
+	}
+
+	v, err := c.backend.Get(ctx, key)
+	switch {
+	case err == ErrNotFound:
+		c.stats.misses.Add(1)
+		return "", err
+	case err != nil:
+		c.stats.errors.Add(1)
+		return "", err
+	}
+	if v, err = decode(key, v); err != nil {
+		c.stats.errors.Add(1)
+		return "", err
+	}
+	c.stats.hits.Add(1)
+
+	// the value read may predate an invalidation received meanwhile, in which
+	// case keeping it locally would serve it until the local ttl runs out.
+	if c.local != nil {
+		c.local.SetIfUnchanged(key, v, gen)
+	}
+	return v, nil
+}
+
+// SetByTags will set cache by given tags.
+// A *TagError is returned when key could only be added to some of the tags.
+func (c *cache) SetByTags(ctx context.Context, key, value string, expiry time.Duration, tags []string) error {
+	t := time.Now()
+	defer c.stats.setLatency.Observe(t)
+
+	stored, err := c.encode(key, value)
+	if err != nil {
+		c.stats.errors.Add(1)
+		return err
+	}
+
+	err = c.backend.SetByTags(ctx, key, stored, expiry, tags)
+	// other instances may hold a stale copy of the key, even when only some tags failed.
+	c.evict(ctx, []string{key})
+	if err != nil {
+		c.stats.errors.Add(1)
+		return err
+	}
+	c.stats.sets.Add(1)
+
+	log.Printf("SetByTags: time take = %dms", time.Since(t).Milliseconds())
+	return nil
+}
+
+// SetManyByTags will set cache for every entry by its tags, e.g. to warm the cache
+// after a deploy. Keys sharing a tag are added to its set at once, and entries are
+// sent in bounded pipelines. It returns one error per entry, nil for the stored ones.
+func (c *cache) SetManyByTags(ctx context.Context, entries []Entry) []error {
+	t := time.Now()
+	defer c.stats.setLatency.Observe(t)
+
+	errs := make([]error, len(entries))
+	encoded := make([]Entry, 0, len(entries))
+	index := make([]int, 0, len(entries))
+	for i, e := range entries {
+		if e.Value, errs[i] = c.encode(e.Key, e.Value); errs[i] == nil {
+			encoded = append(encoded, e)
+			index = append(index, i)
+		}
+	}
+	for i, err := range c.backend.SetManyByTags(ctx, encoded) {
+		errs[index[i]] = err
+	}
+
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+		if errs[i] != nil {
+			c.stats.errors.Add(1)
+		} else {
+			c.stats.sets.Add(1)
+		}
+	}
+	c.evict(ctx, keys)
+
+	log.Printf("SetManyByTags: time take = %dms", time.Since(t).Milliseconds())
+	return errs
+}
+
+// Invalidate will invalidate cache with given tags.
+// Tags may be globs and cascade to their children, see expandTags.
+// A *TagError lists the tags that could not be invalidated, the others are.
+func (c *cache) Invalidate(ctx context.Context, tags []string) error {
+	t := time.Now()
+	defer c.stats.invalidateLatency.Observe(t)
+
+	tags, err := expandTags(tags, func(glob string) ([]string, error) {
+		return c.backend.Tags(ctx, glob)
+	})
+	if err != nil {
+		c.stats.errors.Add(1)
+		return fmt.Errorf("cache: expand tags: %w", err)
+	}
+
+	keys, err := c.backend.Invalidate(ctx, tags)
+	c.stats.invalidations.Add(1)
+	c.stats.invalidatedKeys.Add(int64(len(keys)))
+	c.evict(ctx, keys)
+	if err != nil {
+		c.stats.errors.Add(1)
+		return err
+	}
+
+	log.Printf("Invalidate: time take = %dms", time.Since(t).Milliseconds())
+	return nil
+}
+
+// evict removes keys from the local tier of this and every other instance.
+// Publishing is best effort: instances missing it serve stale entries until
+// their local ttl runs out, so its errors are only logged.
+func (c *cache) evict(ctx context.Context, keys []string) {
+	if c.local == nil || len(keys) == 0 {
+		return
+	}
+
+	c.local.Remove(keys...)
+	if b, ok := c.backend.(broadcaster); ok {
+		if err := b.Publish(ctx, keys); err != nil {
+			log.Printf("error publishing invalidation: %v", err)
+		}
+	}
+}
+
+// listen evicts keys received from other instances from the local tier.
+func (c *cache) listen(ch <-chan []string) {
+	for keys := range ch {
+		c.local.Remove(keys...)
+	}
+}
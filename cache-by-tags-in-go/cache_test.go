@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// newSharedCaches returns n caches with a local tier sharing a redis backend,
+// once each of them listens for invalidations.
+func newSharedCaches(t *testing.T, n int, wrap func(*redisBackend) Backend) []*cache {
+	t.Helper()
+
+	srv := miniredis.RunT(t)
+	caches := make([]*cache, n)
+	for i := range caches {
+		client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+		t.Cleanup(func() { client.Close() })
+
+		var b Backend = newRedisBackend(client)
+		if wrap != nil {
+			b = wrap(b.(*redisBackend))
+		}
+		c := newCache(b, WithLocalCache(10, time.Hour))
+		t.Cleanup(func() { c.Close() })
+		caches[i] = c
+	}
+
+	waitFor(t, func() bool { return srv.PubSubNumSub(invalidationChannel)[invalidationChannel] == n })
+	return caches
+}
+
+// waitFor fails the test unless cond holds within a second.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+	}
+}
+
+// TestLocalEvictionAcrossInstances checks that keys set or invalidated through
+// one cache are evicted from the local tier of the others.
+func TestLocalEvictionAcrossInstances(t *testing.T) {
+	caches := newSharedCaches(t, 2, nil)
+	c1, c2 := caches[0], caches[1]
+
+	// wait for the eviction published by the set, which would drop a value read before it.
+	gen := c2.local.Generation()
+	if err := c1.SetByTags(ctx, "data:key1", "v1", 0, []string{"post1"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return c2.local.Generation() != gen })
+	if v, err := c2.Get(ctx, "data:key1"); err != nil || v != "v1" {
+		t.Fatalf("Get(data:key1) = %q, %v, want %q", v, err, "v1")
+	}
+	if c2.local.Len() != 1 {
+		t.Fatal("the value was not kept in the local tier")
+	}
+
+	gen = c2.local.Generation()
+	if err := c1.SetByTags(ctx, "data:key1", "v2", 0, []string{"post1"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return c2.local.Generation() != gen })
+	if v, err := c2.Get(ctx, "data:key1"); err != nil || v != "v2" {
+		t.Errorf("Get(data:key1) = %q, %v, want %q", v, err, "v2")
+	}
+
+	if err := c1.Invalidate(ctx, []string{"post1"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return c2.local.Len() == 0 })
+	if _, err := c2.Get(ctx, "data:key1"); err != ErrNotFound {
+		t.Errorf("Get(data:key1) error = %v, want ErrNotFound", err)
+	}
+}
+
+// blockingBackend holds its first Get after reading from redis until released.
+type blockingBackend struct {
+	*redisBackend
+	once    sync.Once
+	read    chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingBackend) Get(ctx context.Context, key string) (string, error) {
+	v, err := b.redisBackend.Get(ctx, key)
+	b.once.Do(func() {
+		close(b.read)
+		<-b.release
+	})
+	return v, err
+}
+
+// TestInvalidationDuringGet checks that a value read from the backend before an
+// invalidation that arrives during the read is not kept in the local tier.
+func TestInvalidationDuringGet(t *testing.T) {
+	blocking := &blockingBackend{read: make(chan struct{}), release: make(chan struct{})}
+	caches := newSharedCaches(t, 2, func(r *redisBackend) Backend {
+		if blocking.redisBackend != nil {
+			return r
+		}
+		blocking.redisBackend = r
+		return blocking
+	})
+	reader, writer := caches[0], caches[1]
+
+	gen := reader.local.Generation()
+	if err := writer.SetByTags(ctx, "data:key1", "v1", 0, []string{"post1"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return reader.local.Generation() != gen })
+
+	done := make(chan error)
+	go func() {
+		_, err := reader.Get(ctx, "data:key1")
+		done <- err
+	}()
+	<-blocking.read
+
+	gen = reader.local.Generation()
+	if err := writer.Invalidate(ctx, []string{"post1"}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return reader.local.Generation() != gen })
+	close(blocking.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if n := reader.local.Len(); n != 0 {
+		t.Errorf("local tier holds %d entries, want the stale value dropped", n)
+	}
+	if _, err := reader.Get(ctx, "data:key1"); err != ErrNotFound {
+		t.Errorf("Get(data:key1) error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrValueTooLarge is returned when a value exceeds the size set by WithMaxValueSize.
+var ErrValueTooLarge = errors.New("cache: value too large")
+
+// Stored values carry a two byte marker when they need decoding: markerByte followed
+// by the format. Other values are stored as is, so values written without compression
+// remain readable.
+const (
+	markerByte   = '\x00'
+	formatGzip   = 'z' // the rest of the value is gzip compressed
+	formatEscape = 'r' // the rest of the value is stored as is
+)
+
+// WithCompression gzips values of at least threshold bytes before storing them.
+func WithCompression(threshold int) Option {
+	return func(c *cache) {
+		c.compressAbove = threshold
+	}
+}
+
+// WithMaxValueSize rejects values taking more than max bytes once encoded,
+// i.e. after compression, with ErrValueTooLarge.
+func WithMaxValueSize(max int) Option {
+	return func(c *cache) {
+		c.maxValueSize = max
+	}
+}
+
+// encode returns value as it is to be stored.
+func (c *cache) encode(key, value string) (string, error) {
+	stored := value
+	switch {
+	case c.compressAbove > 0 && len(value) >= c.compressAbove:
+		var buf bytes.Buffer
+		buf.WriteByte(markerByte)
+		buf.WriteByte(formatGzip)
+		zw := gzip.NewWriter(&buf)
+		if _, err := io.WriteString(zw, value); err != nil {
+			return "", fmt.Errorf("cache: compress %s: %w", key, err)
+		}
+		if err := zw.Close(); err != nil {
+			return "", fmt.Errorf("cache: compress %s: %w", key, err)
+		}
+		stored = buf.String()
+	case len(value) > 0 && value[0] == markerByte:
+		// escape values that would otherwise be mistaken for encoded ones.
+		stored = string([]byte{markerByte, formatEscape}) + value
+	}
+
+	if c.maxValueSize > 0 && len(stored) > c.maxValueSize {
+		return "", fmt.Errorf("%w: %s takes %d bytes, max is %d", ErrValueTooLarge, key, len(stored), c.maxValueSize)
+	}
+	return stored, nil
+}
+
+// decode returns the value that was encoded into stored.
+func decode(key, stored string) (string, error) {
+	if len(stored) < 2 || stored[0] != markerByte {
+		return stored, nil
+	}
+
+	switch stored[1] {
+	case formatGzip:
+		zr, err := gzip.NewReader(strings.NewReader(stored[2:]))
+		if err != nil {
+			return "", fmt.Errorf("cache: decompress %s: %w", key, err)
+		}
+		var b strings.Builder
+		if _, err := io.Copy(&b, zr); err != nil {
+			return "", fmt.Errorf("cache: decompress %s: %w", key, err)
+		}
+		return b.String(), nil
+	case formatEscape:
+		return stored[2:], nil
+	}
+	return stored, nil
+}
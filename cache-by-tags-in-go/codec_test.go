@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestCompression checks that values round trip whether or not they get compressed.
+func TestCompression(t *testing.T) {
+	b := newMemoryBackend()
+	c := newCache(b, WithCompression(64))
+
+	var tests = []struct {
+		value      string
+		compressed bool
+	}{
+		{value: "short", compressed: false},
+		{value: strings.Repeat(`{"comment":"hello"}`, 100), compressed: true},
+		{value: "\x00z looks encoded", compressed: false},
+		{value: "", compressed: false},
+	}
+
+	for i, td := range tests {
+		key := fmt.Sprintf("data:key%d", i)
+		t.Run(key, func(t *testing.T) {
+			if err := c.SetByTags(ctx, key, td.value, 0, nil); err != nil {
+				t.Fatalf("SetByTags: %v", err)
+			}
+
+			stored, _ := b.Get(ctx, key)
+			if got := strings.HasPrefix(stored, "\x00z"); got != td.compressed {
+				t.Errorf("stored compressed = %t, want %t", got, td.compressed)
+			}
+
+			if got, err := c.Get(ctx, key); err != nil || got != td.value {
+				t.Errorf("Get = %q, %v, want %q", got, err, td.value)
+			}
+		})
+	}
+}
+
+// TestMaxValueSize checks that values taking more than the maximum are rejected.
+func TestMaxValueSize(t *testing.T) {
+	c := newCache(newMemoryBackend(), WithCompression(64), WithMaxValueSize(100))
+
+	if err := c.SetByTags(ctx, "data:key1", strings.Repeat("a", 1000), 0, nil); err != nil {
+		t.Errorf("compressible value: %v, want no error", err)
+	}
+
+	noise := make([]byte, 200)
+	for i := range noise {
+		noise[i] = byte(i*7919 + i*i)
+	}
+	errs := c.SetManyByTags(ctx, []Entry{
+		{Key: "data:key2", Value: "small"},
+		{Key: "data:key3", Value: string(noise)},
+	})
+	if errs[0] != nil {
+		t.Errorf("small value: %v, want no error", errs[0])
+	}
+	if !errors.Is(errs[1], ErrValueTooLarge) {
+		t.Errorf("large value: %v, want ErrValueTooLarge", errs[1])
+	}
+	if _, err := c.Get(ctx, "data:key3"); err != ErrNotFound {
+		t.Errorf("Get of a rejected value error = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lru is a size bounded, in-process cache that evicts the least recently used entry.
+type lru struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+	ll      *list.List
+	entries map[string]*list.Element
+
+	// gen counts the calls to Remove, so that SetIfUnchanged can tell a value
+	// read from the backend may have been invalidated meanwhile.
+	gen uint64
+}
+
+type lruEntry struct {
+	key      string
+	value    string
+	expireAt time.Time
+}
+
+// newLRU returns an lru holding at most size entries, each for at most ttl.
+func newLRU(size int, ttl time.Duration) *lru {
+	return &lru{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value for key if present and not expired.
+func (l *lru) Get(key string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.entries[key]
+	if !ok {
+		return "", false
+	}
+
+	e := el.Value.(*lruEntry)
+	if l.now().After(e.expireAt) {
+		l.removeElement(el)
+		return "", false
+	}
+
+	l.ll.MoveToFront(el)
+	return e.value, true
+}
+
+// Generation returns a value that changes whenever keys are removed.
+func (l *lru) Generation() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.gen
+}
+
+// SetIfUnchanged sets the value for key like Set, unless keys were removed since
+// Generation returned gen, in which case the value may be stale and is dropped.
+func (l *lru) SetIfUnchanged(key, value string, gen uint64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.gen != gen {
+		return false
+	}
+	l.set(key, value)
+	return true
+}
+
+// Set adds or replaces the value for key.
+func (l *lru) Set(key, value string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.set(key, value)
+}
+
+func (l *lru) set(key, value string) {
+	expireAt := l.now().Add(l.ttl)
+	if el, ok := l.entries[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.value, e.expireAt = value, expireAt
+		l.ll.MoveToFront(el)
+		return
+	}
+
+	l.entries[key] = l.ll.PushFront(&lruEntry{key: key, value: value, expireAt: expireAt})
+	for l.ll.Len() > l.size {
+		l.removeElement(l.ll.Back())
+	}
+}
+
+// Remove evicts the given keys.
+func (l *lru) Remove(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.gen++
+	for _, key := range keys {
+		if el, ok := l.entries[key]; ok {
+			l.removeElement(el)
+		}
+	}
+}
+
+// Len returns the number of entries held.
+func (l *lru) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ll.Len()
+}
+
+func (l *lru) removeElement(el *list.Element) {
+	l.ll.Remove(el)
+	delete(l.entries, el.Value.(*lruEntry).key)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestLRUEviction checks that the least recently used entry is evicted first.
+func TestLRUEviction(t *testing.T) {
+	l := newLRU(2, time.Minute)
+	l.Set("key1", "v1")
+	l.Set("key2", "v2")
+	l.Get("key1")
+	l.Set("key3", "v3")
+
+	var tests = []struct {
+		key string
+		ok  bool
+	}{
+		{key: "key1", ok: true},
+		{key: "key2", ok: false},
+		{key: "key3", ok: true},
+	}
+	for _, td := range tests {
+		testname := fmt.Sprintf("when getting %s", td.key)
+		t.Run(testname, func(t *testing.T) {
+			if _, ok := l.Get(td.key); ok != td.ok {
+				t.Errorf("Get(%s) found = %t, want %t", td.key, ok, td.ok)
+			}
+		})
+	}
+	if n := l.Len(); n != 2 {
+		t.Errorf("Len() = %d, want 2", n)
+	}
+}
+
+// TestLRUExpiry checks that entries are dropped once their ttl has passed,
+// and that replacing an entry renews its ttl.
+func TestLRUExpiry(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	l := newLRU(10, time.Minute)
+	l.now = clock.Now
+
+	l.Set("key1", "v1")
+	l.Set("key2", "v2")
+	clock.Advance(40 * time.Second)
+	l.Set("key2", "v2")
+	clock.Advance(30 * time.Second)
+
+	if _, ok := l.Get("key1"); ok {
+		t.Error("Get(key1) found an expired entry")
+	}
+	if v, ok := l.Get("key2"); !ok || v != "v2" {
+		t.Errorf("Get(key2) = %q, %t, want the renewed entry", v, ok)
+	}
+	if n := l.Len(); n != 1 {
+		t.Errorf("Len() = %d, want 1", n)
+	}
+}
+
+// TestLRUSetIfUnchanged checks that a value read before a removal is not kept.
+func TestLRUSetIfUnchanged(t *testing.T) {
+	l := newLRU(10, time.Minute)
+
+	gen := l.Generation()
+	if !l.SetIfUnchanged("key1", "v1", gen) {
+		t.Error("SetIfUnchanged dropped a value without removals")
+	}
+
+	gen = l.Generation()
+	l.Remove("key2")
+	if l.SetIfUnchanged("key2", "stale", gen) {
+		t.Error("SetIfUnchanged kept a value read before a removal")
+	}
+	if _, ok := l.Get("key2"); ok {
+		t.Error("Get(key2) found a stale value")
+	}
+}
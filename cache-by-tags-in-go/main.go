@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
+	"expvar"
+	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"sort"
 	"time"
 
-	"github.com/go-redis/redis/v7"
+	"github.com/go-redis/redis/v8"
 )
 
 func main() {
@@ -17,69 +23,117 @@ func main() {
 		},
 	)
 
-	app := cache{client: client}
+	app := newCache(
+		newRedisBackend(client),
+		WithLocalCache(1000, time.Minute),
+		WithCompression(4<<10),    // compress values from 4KiB
+		WithMaxValueSize(512<<10), // reject values over 512KiB
+	)
+	defer app.Close()
+
+	ctx := context.Background()
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "inspect":
+			inspect(ctx, app, os.Args[2:])
+		case "serve":
+			serve(app, os.Args[2:])
+		default:
+			log.Fatalf("unknown command %q, want inspect or serve", os.Args[1])
+		}
+		return
+	}
+
+	demo(ctx, app)
+}
 
-	k := "data:key1"
+// demo stores and invalidates a few keys.
+func demo(ctx context.Context, app *cache) {
 	v := "randomstringdata"
 
-	tags := make([]string, 0)
-	for i := 1; i <= 60; i++ {
-		tags = append(tags, fmt.Sprintf("post%d", i))
+	// key1..key3 share most of their tags, so they are written in one batch.
+	entries := make([]Entry, 0, 3)
+	for i, from := range []int{1, 10, 50} {
+		tags := make([]string, 0)
+		for j := from; j <= 60; j++ {
+			tags = append(tags, fmt.Sprintf("post%d", j))
+		}
+		entries = append(entries, Entry{
+			Key:    fmt.Sprintf("data:key%d", i+1),
+			Value:  v,
+			Expiry: 30 * time.Minute,
+			Tags:   tags,
+		})
+	}
+	for i, err := range app.SetManyByTags(ctx, entries) {
+		if err != nil {
+			log.Printf("error setting %s: %v", entries[i].Key, err)
+		}
 	}
-	app.SetByTags(k, v, 30*time.Minute, tags)
 
-	k = "data:key2"
-	tags = nil
-	for i := 10; i <= 60; i++ {
+	tags := make([]string, 0)
+	for i := 1; i <= 10; i++ {
 		tags = append(tags, fmt.Sprintf("post%d", i))
 	}
-	app.SetByTags(k, v, 30*time.Minute, tags)
+	if err := app.Invalidate(ctx, tags); err != nil {
+		log.Printf("error invalidating cache: %v", err)
+	}
 
-	k = "data:key3"
-	tags = nil
-	for i := 50; i <= 60; i++ {
-		tags = append(tags, fmt.Sprintf("post%d", i))
+	if err := app.SetByTags(ctx, "data:key4", v, 30*time.Minute, []string{"post:42:comments:1", "post:42:comments:2"}); err != nil {
+		log.Printf("error setting cache: %v", err)
+	}
+	if err := app.SetByTags(ctx, "data:key5", v, 30*time.Minute, []string{"post:42:likes"}); err != nil {
+		log.Printf("error setting cache: %v", err)
 	}
-	app.SetByTags(k, v, 30*time.Minute, tags)
 
-	tags = nil
-	for i := 1; i <= 10; i++ {
-		tags = append(tags, fmt.Sprintf("post%d", i))
+	// invalidating a parent tag cascades to every tag nested under it.
+	if err := app.Invalidate(ctx, []string{"post:42"}); err != nil {
+		log.Printf("error invalidating cache: %v", err)
 	}
-	app.Invalidate(tags)
-}
 
-type cache struct {
-	client *redis.Client
+	if _, err := app.Get(ctx, "data:key3"); err != nil {
+		log.Printf("error getting data:key3: %v", err)
+	}
 }
 
-// SetByTags will set cache by given tags.
-func (c *cache) SetByTags(key, value string, expiry time.Duration, tags []string) {
-	t := time.Now()
+// inspect prints the keys held by a tag or the tags carried by a key.
+func inspect(ctx context.Context, app *cache, args []string) {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	tag := fs.String("tag", "", "print the keys held by this tag")
+	key := fs.String("key", "", "print the tags carried by this key")
+	fs.Parse(args)
 
-	pipe := c.client.TxPipeline()
-	for _, tag := range tags {
-		pipe.SAdd("comment_by_tags:"+tag, key)
+	var (
+		out []string
+		err error
+	)
+	switch {
+	case *tag != "":
+		out, err = app.backend.Keys(ctx, *tag)
+	case *key != "":
+		out, err = app.backend.KeyTags(ctx, *key)
+	default:
+		fs.Usage()
+		os.Exit(2)
 	}
-	pipe.Set(key, value, expiry)
-
-	if _, err := pipe.Exec(); err != nil {
-		log.Printf("error in pipeline: %v", err)
+	if err != nil {
+		log.Fatalf("error inspecting cache: %v", err)
 	}
 
-	log.Printf("SetByTags: time take = %dms", time.Since(t).Milliseconds())
+	sort.Strings(out)
+	for _, s := range out {
+		fmt.Println(s)
+	}
 }
 
-// Invalidate will invalidate cache with given tags.
-func (c *cache) Invalidate(tags []string) {
-	t := time.Now()
-	keys := make([]string, 0)
-	for _, tag := range tags {
-		tagKey := "comment_by_tags:" + tag
-		k, _ := c.client.SMembers(tagKey).Result()
-		keys = append(keys, tagKey)
-		keys = append(keys, k...)
-	}
-	c.client.Del(keys...)
-	log.Printf("Invalidate: time take = %dms", time.Since(t).Milliseconds())
+// serve exposes the cache statistics on /debug/vars.
+func serve(app *cache, args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	fs.Parse(args)
+
+	expvar.Publish("tag_cache", app.Stats())
+	log.Printf("serving cache statistics on %s/debug/vars", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
 }
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryBackend is a Backend held entirely in process memory.
+// It is meant for tests and for services that run without redis.
+type memoryBackend struct {
+	mu      sync.Mutex
+	now     func() time.Time
+	values  map[string]memoryEntry
+	tags    map[string]*memoryTagSet
+	keyTags map[string]map[string]struct{}
+}
+
+type memoryEntry struct {
+	value    string
+	expireAt time.Time // zero means no expiry
+}
+
+type memoryTagSet struct {
+	keys     map[string]struct{}
+	expireAt time.Time // zero means no expiry
+}
+
+// newMemoryBackend returns an empty memoryBackend.
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{
+		now:     time.Now,
+		values:  make(map[string]memoryEntry),
+		tags:    make(map[string]*memoryTagSet),
+		keyTags: make(map[string]map[string]struct{}),
+	}
+}
+
+func (m *memoryBackend) Get(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.values[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	if m.expired(e.expireAt) {
+		delete(m.values, key)
+		delete(m.keyTags, key)
+		return "", ErrNotFound
+	}
+	return e.value, nil
+}
+
+func (m *memoryBackend) SetByTags(ctx context.Context, key, value string, expiry time.Duration, tags []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var expireAt time.Time
+	if expiry > 0 {
+		expireAt = m.now().Add(expiry)
+	}
+
+	for _, tag := range tags {
+		set := m.tagSet(tag)
+		if set == nil {
+			set = &memoryTagSet{keys: make(map[string]struct{}), expireAt: expireAt}
+			m.tags[tag] = set
+		} else if !set.expireAt.IsZero() && (expireAt.IsZero() || expireAt.After(set.expireAt)) {
+			// the set lives as long as its longest lived member.
+			set.expireAt = expireAt
+		}
+		set.keys[key] = struct{}{}
+		addMember(m.keyTags, key, tag)
+	}
+
+	m.values[key] = memoryEntry{value: value, expireAt: expireAt}
+	return nil
+}
+
+func (m *memoryBackend) SetManyByTags(ctx context.Context, entries []Entry) []error {
+	errs := make([]error, len(entries))
+	for i, e := range entries {
+		errs[i] = m.SetByTags(ctx, e.Key, e.Value, e.Expiry, e.Tags)
+	}
+	return errs
+}
+
+func (m *memoryBackend) Invalidate(ctx context.Context, tags []string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]string, 0)
+	for _, tag := range tags {
+		set := m.tagSet(tag)
+		if set == nil {
+			continue
+		}
+		for key := range set.keys {
+			keys = append(keys, key)
+			delete(m.values, key)
+			delete(m.keyTags, key)
+		}
+		delete(m.tags, tag)
+	}
+	return keys, nil
+}
+
+func (m *memoryBackend) Tags(ctx context.Context, glob string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tags := make([]string, 0)
+	for tag := range m.tags {
+		if m.tagSet(tag) != nil && matchGlob(glob, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+func (m *memoryBackend) Keys(ctx context.Context, tag string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	set := m.tagSet(tag)
+	if set == nil {
+		return []string{}, nil
+	}
+	return members(set.keys), nil
+}
+
+func (m *memoryBackend) KeyTags(ctx context.Context, key string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return members(m.keyTags[key]), nil
+}
+
+// tagSet returns the set of tag, or nil if it does not exist or has expired.
+func (m *memoryBackend) tagSet(tag string) *memoryTagSet {
+	set, ok := m.tags[tag]
+	if !ok {
+		return nil
+	}
+	if m.expired(set.expireAt) {
+		delete(m.tags, tag)
+		return nil
+	}
+	return set
+}
+
+// expired reports whether expireAt has passed, zero meaning never.
+func (m *memoryBackend) expired(expireAt time.Time) bool {
+	return !expireAt.IsZero() && !m.now().Before(expireAt)
+}
+
+// addMember adds member to the set stored under name in sets.
+func addMember(sets map[string]map[string]struct{}, name, member string) {
+	set, ok := sets[name]
+	if !ok {
+		set = make(map[string]struct{})
+		sets[name] = set
+	}
+	set[member] = struct{}{}
+}
+
+func members(set map[string]struct{}) []string {
+	out := make([]string, 0, len(set))
+	for m := range set {
+		out = append(out, m)
+	}
+	return out
+}
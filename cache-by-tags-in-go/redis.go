@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// setManyChunkSize is the number of entries SetManyByTags sends per pipeline.
+const setManyChunkSize = 100
+
+// addToTagScript adds ARGV[2:] to the tag set KEYS[1] and makes the set live at least
+// ARGV[1] milliseconds, 0 meaning forever. A set without expiry holds a key without
+// expiry, so it is left as is.
+var addToTagScript = `
+local ttl = redis.call('PTTL', KEYS[1])
+redis.call('SADD', KEYS[1], unpack(ARGV, 2))
+local expiry = tonumber(ARGV[1])
+if expiry == 0 then
+	redis.call('PERSIST', KEYS[1])
+elseif ttl == -2 or (ttl >= 0 and ttl < expiry) then
+	redis.call('PEXPIRE', KEYS[1], expiry)
+end
+return 1
+`
+
+// pruneTagScript removes the tag ARGV[1] from the index KEYS[1] when its set KEYS[2]
+// no longer exists, having expired, and returns whether the set exists. Running it
+// as a script keeps a set created meanwhile from losing its index entry.
+var pruneTagScript = `
+if redis.call('EXISTS', KEYS[2]) == 1 then
+	return 1
+end
+redis.call('SREM', KEYS[1], ARGV[1])
+return 0
+`
+
+// redisBackend keeps values as redis strings and tags as redis sets.
+type redisBackend struct {
+	client  redis.UniversalClient
+	layout  keyLayout
+	cluster bool
+}
+
+// newRedisBackend returns a Backend using the given single node redis client.
+func newRedisBackend(client *redis.Client) *redisBackend {
+	return &redisBackend{client: client, layout: flatLayout}
+}
+
+// newRedisClusterBackend returns a Backend using the given redis cluster client.
+// Tag sets are laid out with hashTagLayout. Writes touching several slots run as one
+// transaction per slot, so they are only atomic for keys sharing the tag sets' slot.
+func newRedisClusterBackend(client *redis.ClusterClient) *redisBackend {
+	return &redisBackend{client: client, layout: hashTagLayout, cluster: true}
+}
+
+func (r *redisBackend) Get(ctx context.Context, key string) (string, error) {
+	v, err := r.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", ErrNotFound
+	}
+	return v, err
+}
+
+func (r *redisBackend) SetByTags(ctx context.Context, key, value string, expiry time.Duration, tags []string) error {
+	pipe := r.client.TxPipeline()
+	sadds := make(map[string]*redis.Cmd, len(tags))
+	for _, tag := range tags {
+		sadds[tag] = r.addToTag(ctx, pipe, tag, expiry, key)
+	}
+	if len(tags) > 0 {
+		pipe.SAdd(ctx, r.indexKey(), toInterfaces(tags)...)
+		pipe.SAdd(ctx, r.keyTagsKey(key), toInterfaces(tags)...)
+		if expiry > 0 {
+			pipe.Expire(ctx, r.keyTagsKey(key), expiry)
+		}
+	}
+	set := pipe.Set(ctx, key, value, expiry)
+
+	_, err := pipe.Exec(ctx)
+	if err == nil {
+		return nil
+	}
+	if set.Err() != nil {
+		return fmt.Errorf("cache: set %s: %w", key, err)
+	}
+
+	// on a cluster each slot runs its own transaction, so only some tags may have failed.
+	failed := make(map[string]error)
+	for tag, cmd := range sadds {
+		if cmd.Err() != nil {
+			failed[tag] = cmd.Err()
+		}
+	}
+	if len(failed) == 0 {
+		return fmt.Errorf("cache: set %s: %w", key, err)
+	}
+	return &TagError{Op: "set", Tags: failed}
+}
+
+func (r *redisBackend) SetManyByTags(ctx context.Context, entries []Entry) []error {
+	errs := make([]error, len(entries))
+	for start := 0; start < len(entries); start += setManyChunkSize {
+		end := start + setManyChunkSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		r.setChunk(ctx, entries[start:end], errs[start:end])
+	}
+	return errs
+}
+
+// setChunk stores entries in a single pipeline, adding the keys of each tag with
+// one command, and records the outcome of entries[i] in errs[i].
+func (r *redisBackend) setChunk(ctx context.Context, entries []Entry, errs []error) {
+	type tagGroup struct {
+		keys    []interface{}
+		entries []int
+		expiry  time.Duration
+		forever bool
+	}
+
+	groups := make(map[string]*tagGroup)
+	order := make([]string, 0)
+	for i, e := range entries {
+		for _, tag := range e.Tags {
+			g, ok := groups[tag]
+			if !ok {
+				g = &tagGroup{}
+				groups[tag] = g
+				order = append(order, tag)
+			}
+			g.keys = append(g.keys, e.Key)
+			g.entries = append(g.entries, i)
+			// the set must outlive its longest lived key, or live forever for one without expiry.
+			if e.Expiry <= 0 {
+				g.forever = true
+			} else if e.Expiry > g.expiry {
+				g.expiry = e.Expiry
+			}
+		}
+	}
+
+	pipe := r.client.Pipeline()
+	entryCmds := make([][]redis.Cmder, len(entries))
+	for i, e := range entries {
+		entryCmds[i] = append(entryCmds[i], pipe.Set(ctx, e.Key, e.Value, e.Expiry))
+		if len(e.Tags) > 0 {
+			entryCmds[i] = append(entryCmds[i], pipe.SAdd(ctx, r.keyTagsKey(e.Key), toInterfaces(e.Tags)...))
+			if e.Expiry > 0 {
+				entryCmds[i] = append(entryCmds[i], pipe.Expire(ctx, r.keyTagsKey(e.Key), e.Expiry))
+			}
+		}
+	}
+
+	tagCmds := make(map[string]*redis.Cmd, len(groups))
+	for _, tag := range order {
+		g := groups[tag]
+		expiry := g.expiry
+		if g.forever {
+			expiry = 0
+		}
+		tagCmds[tag] = r.addToTag(ctx, pipe, tag, expiry, g.keys...)
+	}
+	var index *redis.IntCmd
+	if len(order) > 0 {
+		index = pipe.SAdd(ctx, r.indexKey(), toInterfaces(order)...)
+	}
+
+	pipe.Exec(ctx)
+
+	failedTags := make([]map[string]error, len(entries))
+	for tag, cmd := range tagCmds {
+		if cmd.Err() == nil {
+			continue
+		}
+		for _, i := range groups[tag].entries {
+			if failedTags[i] == nil {
+				failedTags[i] = make(map[string]error)
+			}
+			failedTags[i][tag] = cmd.Err()
+		}
+	}
+
+	for i, e := range entries {
+		for _, cmd := range entryCmds[i] {
+			if cmd.Err() != nil {
+				errs[i] = fmt.Errorf("cache: set %s: %w", e.Key, cmd.Err())
+				break
+			}
+		}
+		if errs[i] == nil && failedTags[i] != nil {
+			errs[i] = &TagError{Op: "set", Tags: failedTags[i]}
+		}
+	}
+	if index != nil && index.Err() != nil {
+		log.Printf("error adding tags to the index: %v", index.Err())
+	}
+}
+
+// addToTag queues the addition of keys to the set of tag, extending the set's
+// expiry to cover expiry, 0 meaning forever.
+func (r *redisBackend) addToTag(ctx context.Context, pipe redis.Pipeliner, tag string, expiry time.Duration, keys ...interface{}) *redis.Cmd {
+	ttl := expiry.Milliseconds()
+	if expiry > 0 && ttl == 0 {
+		ttl = 1
+	}
+	return pipe.Eval(ctx, addToTagScript, []string{r.layout(tag)}, append([]interface{}{ttl}, keys...)...)
+}
+
+func (r *redisBackend) Invalidate(ctx context.Context, tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return []string{}, nil
+	}
+
+	members := make(map[string]*redis.StringSliceCmd, len(tags))
+	pipe := r.client.Pipeline()
+	for _, tag := range tags {
+		members[tag] = pipe.SMembers(ctx, r.layout(tag))
+	}
+	pipe.Exec(ctx)
+
+	// delete every tag set along with its keys, keeping track of the commands of each tag.
+	failed := make(map[string]error)
+	dels := make(map[string][]*redis.IntCmd, len(tags))
+	pipe = r.client.Pipeline()
+	for _, tag := range tags {
+		keys, err := members[tag].Result()
+		if err != nil {
+			failed[tag] = err
+			continue
+		}
+
+		del := []string{r.layout(tag)}
+		for _, key := range keys {
+			del = append(del, key, r.keyTagsKey(key))
+		}
+		for _, group := range r.groupKeys(del) {
+			dels[tag] = append(dels[tag], pipe.Del(ctx, group...))
+		}
+	}
+	if len(dels) > 0 {
+		pipe.Exec(ctx)
+	}
+
+	keys := make([]string, 0)
+	done := make([]string, 0, len(dels))
+	for tag, cmds := range dels {
+		for _, cmd := range cmds {
+			if cmd.Err() != nil {
+				failed[tag] = cmd.Err()
+				break
+			}
+		}
+		if failed[tag] == nil {
+			done = append(done, tag)
+			keys = append(keys, members[tag].Val()...)
+		}
+	}
+
+	if len(done) > 0 {
+		if err := r.client.SRem(ctx, r.indexKey(), toInterfaces(done)...).Err(); err != nil {
+			log.Printf("error removing invalidated tags from the index: %v", err)
+		}
+	}
+	if len(failed) > 0 {
+		return keys, &TagError{Op: "invalidate", Tags: failed}
+	}
+	return keys, nil
+}
+
+// groupKeys splits keys into one group per slot on a cluster to avoid CROSSSLOT errors.
+func (r *redisBackend) groupKeys(keys []string) [][]string {
+	if !r.cluster {
+		return [][]string{keys}
+	}
+	return groupBySlot(keys)
+}
+
+// Tags returns the indexed tags matching glob whose set still exists, dropping
+// the others from the index.
+func (r *redisBackend) Tags(ctx context.Context, glob string) ([]string, error) {
+	candidates := make([]string, 0)
+	iter := r.client.SScan(ctx, r.indexKey(), 0, glob, 100).Iterator()
+	for iter.Next(ctx) {
+		candidates = append(candidates, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	pipe := r.client.Pipeline()
+	exists := make([]*redis.Cmd, len(candidates))
+	for i, tag := range candidates {
+		exists[i] = pipe.Eval(ctx, pruneTagScript, []string{r.indexKey(), r.layout(tag)}, tag)
+	}
+	if len(candidates) > 0 {
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	tags := make([]string, 0, len(candidates))
+	for i, tag := range candidates {
+		if n, _ := exists[i].Int(); n == 1 {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+func (r *redisBackend) Keys(ctx context.Context, tag string) ([]string, error) {
+	return r.client.SMembers(ctx, r.layout(tag)).Result()
+}
+
+func (r *redisBackend) KeyTags(ctx context.Context, key string) ([]string, error) {
+	return r.client.SMembers(ctx, r.keyTagsKey(key)).Result()
+}
+
+// keyTagsKey returns the key of the set holding the tags of key.
+func (r *redisBackend) keyTagsKey(key string) string {
+	return strings.TrimSuffix(r.layout(""), ":") + "_keys:" + key
+}
+
+// indexKey returns the key of the set holding every known tag.
+// It shares the hash tag of the tag sets, if any, so it lives in their slot.
+func (r *redisBackend) indexKey() string {
+	return strings.TrimSuffix(r.layout(""), ":") + "_index"
+}
+
+func (r *redisBackend) Publish(ctx context.Context, keys []string) error {
+	payload, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	return r.client.Publish(ctx, invalidationChannel, payload).Err()
+}
+
+func (r *redisBackend) Subscribe(ctx context.Context) (<-chan []string, func() error) {
+	pubsub := r.client.Subscribe(ctx, invalidationChannel)
+	ch := make(chan []string)
+
+	go func() {
+		defer close(ch)
+		for msg := range pubsub.Channel() {
+			var keys []string
+			if err := json.Unmarshal([]byte(msg.Payload), &keys); err != nil {
+				log.Printf("error decoding invalidation message: %v", err)
+				continue
+			}
+			ch <- keys
+		}
+	}()
+
+	return ch, pubsub.Close
+}
+
+func toInterfaces(s []string) []interface{} {
+	out := make([]interface{}, len(s))
+	for i, v := range s {
+		out[i] = v
+	}
+	return out
+}
@@ -0,0 +1,64 @@
+package main
+
+import "strings"
+
+// slotCount is the number of hash slots in a redis cluster.
+const slotCount = 16384
+
+// keyLayout returns the redis key of the set holding the keys of tag.
+type keyLayout func(tag string) string
+
+// flatLayout hashes every tag set on its own, spreading tags over all slots.
+func flatLayout(tag string) string {
+	return tagPrefix + tag
+}
+
+// hashTagLayout keeps every tag set in the same cluster slot by wrapping the prefix
+// in a hash tag. Data keys that carry the same hash tag, e.g. "{comment_by_tags}:key1",
+// land in that slot too, so SetByTags and Invalidate stay atomic on a cluster.
+func hashTagLayout(tag string) string {
+	return "{" + strings.TrimSuffix(tagPrefix, ":") + "}:" + tag
+}
+
+// keySlot returns the cluster slot of key, honouring hash tags.
+func keySlot(key string) int {
+	if s := strings.IndexByte(key, '{'); s > -1 {
+		if e := strings.IndexByte(key[s+1:], '}'); e > 0 {
+			key = key[s+1 : s+e+1]
+		}
+	}
+	return int(crc16(key) % slotCount)
+}
+
+// groupBySlot splits keys into groups that each map to a single cluster slot.
+func groupBySlot(keys []string) [][]string {
+	index := make(map[int]int)
+	groups := make([][]string, 0)
+	for _, key := range keys {
+		slot := keySlot(key)
+		i, ok := index[slot]
+		if !ok {
+			i = len(groups)
+			index[slot] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], key)
+	}
+	return groups
+}
+
+// crc16 implements the CRC16-CCITT (XMODEM) checksum used by redis cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
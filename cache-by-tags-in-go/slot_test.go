@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestKeySlot checks slots against the values documented for redis cluster.
+func TestKeySlot(t *testing.T) {
+	var tests = []struct {
+		key  string
+		want int
+	}{
+		{key: "123456789", want: 12739},
+		{key: "foo", want: 12182},
+		{key: "{user1000}.following", want: keySlot("user1000")},
+		// an empty hash tag does not count, so the whole key is hashed.
+		{key: "foo{}{bar}", want: 8363},
+		{key: hashTagLayout("post1"), want: keySlot(hashTagLayout("post2"))},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when testing key `%s`", td.key)
+		t.Run(testname, func(t *testing.T) {
+			if got := keySlot(td.key); got != td.want {
+				t.Errorf("got %d, want %d", got, td.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// maxStatsTags caps the number of tags whose cardinality is reported,
+// as computing it costs a round trip per tag.
+const maxStatsTags = 100
+
+// latencyBuckets are the upper bounds of the latency histogram buckets.
+var latencyBuckets = [...]time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+}
+
+// stats holds the counters and latency histograms of a cache.
+type stats struct {
+	hits            expvar.Int
+	localHits       expvar.Int
+	misses          expvar.Int
+	errors          expvar.Int
+	sets            expvar.Int
+	invalidations   expvar.Int
+	invalidatedKeys expvar.Int
+
+	getLatency        histogram
+	setLatency        histogram
+	invalidateLatency histogram
+}
+
+// Stats returns the statistics of the cache as an expvar.Map, ready to be
+// published with expvar.Publish and served by expvar.Handler.
+func (c *cache) Stats() *expvar.Map {
+	s := &c.stats
+	m := new(expvar.Map).Init()
+	m.Set("hits", &s.hits)
+	m.Set("local_hits", &s.localHits)
+	m.Set("misses", &s.misses)
+	m.Set("errors", &s.errors)
+	m.Set("sets", &s.sets)
+	m.Set("invalidations", &s.invalidations)
+	m.Set("invalidated_keys", &s.invalidatedKeys)
+	m.Set("get_latency", &s.getLatency)
+	m.Set("set_latency", &s.setLatency)
+	m.Set("invalidate_latency", &s.invalidateLatency)
+	m.Set("tag_cardinality", expvar.Func(c.tagCardinality))
+	if c.local != nil {
+		m.Set("local_entries", expvar.Func(func() interface{} { return c.local.Len() }))
+	}
+	return m
+}
+
+// tagCardinality returns the number of keys held by each known tag, for at most maxStatsTags tags.
+func (c *cache) tagCardinality() interface{} {
+	ctx := context.Background()
+	tags, err := c.backend.Tags(ctx, "*")
+	if err != nil {
+		log.Printf("error listing tags: %v", err)
+		return nil
+	}
+	if len(tags) > maxStatsTags {
+		tags = tags[:maxStatsTags]
+	}
+
+	cardinality := make(map[string]int, len(tags))
+	for _, tag := range tags {
+		keys, err := c.backend.Keys(ctx, tag)
+		if err != nil {
+			log.Printf("error listing keys of tag %s: %v", tag, err)
+			continue
+		}
+		cardinality[tag] = len(keys)
+	}
+	return cardinality
+}
+
+// histogram counts observed durations in latencyBuckets.
+type histogram struct {
+	mu     sync.Mutex
+	counts [len(latencyBuckets) + 1]int64 // the last one counts anything slower
+	count  int64
+	sum    time.Duration
+}
+
+// Observe records the time elapsed since start.
+func (h *histogram) Observe(start time.Time) {
+	d := time.Since(start)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := 0
+	for i < len(latencyBuckets) && d > latencyBuckets[i] {
+		i++
+	}
+	h.counts[i]++
+	h.count++
+	h.sum += d
+}
+
+// String implements expvar.Var.
+func (h *histogram) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	buckets := make(map[string]int64, len(h.counts))
+	for i, b := range latencyBuckets {
+		buckets[fmt.Sprintf("le_%s", b)] = h.counts[i]
+	}
+	buckets["inf"] = h.counts[len(latencyBuckets)]
+
+	v, _ := json.Marshal(struct {
+		Buckets map[string]int64 `json:"buckets"`
+		Count   int64            `json:"count"`
+		SumMs   int64            `json:"sum_ms"`
+	}{buckets, h.count, h.sum.Milliseconds()})
+	return string(v)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestStats checks the counters reported by Stats.
+func TestStats(t *testing.T) {
+	c := newCache(newMemoryBackend(), WithLocalCache(10, time.Minute))
+	c.SetByTags(ctx, "data:key1", "v1", 0, []string{"post1", "post2"})
+	c.SetByTags(ctx, "data:key2", "v2", 0, []string{"post2"})
+	c.Get(ctx, "data:key1")
+	c.Get(ctx, "data:key1")
+	c.Get(ctx, "data:missing")
+	c.Invalidate(ctx, []string{"post1"})
+
+	var got struct {
+		Hits            int64          `json:"hits"`
+		LocalHits       int64          `json:"local_hits"`
+		Misses          int64          `json:"misses"`
+		Sets            int64          `json:"sets"`
+		InvalidatedKeys int64          `json:"invalidated_keys"`
+		TagCardinality  map[string]int `json:"tag_cardinality"`
+		GetLatency      struct {
+			Count int64 `json:"count"`
+		} `json:"get_latency"`
+	}
+	if err := json.Unmarshal([]byte(c.Stats().String()), &got); err != nil {
+		t.Fatalf("decoding stats: %v", err)
+	}
+
+	if got.Hits != 2 || got.LocalHits != 1 || got.Misses != 1 || got.Sets != 2 || got.InvalidatedKeys != 1 {
+		t.Errorf("got counters %+v", got)
+	}
+	if got.GetLatency.Count != 3 {
+		t.Errorf("got %d observed gets, want 3", got.GetLatency.Count)
+	}
+	if n := got.TagCardinality["post2"]; n != 2 {
+		t.Errorf("got cardinality %d for post2, want 2", n)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import "strings"
+
+// tagSeparator separates the levels of a hierarchical tag such as "post:42:comments".
+const tagSeparator = ":"
+
+// isGlob reports whether tag contains glob metacharacters.
+func isGlob(tag string) bool {
+	return strings.ContainsAny(tag, `*?[\`)
+}
+
+// expandTags resolves invalidation patterns into the concrete tags they cover.
+// A pattern is either a tag or a glob using redis syntax (*, ?, [...] and \ escapes),
+// and it cascades to every tag nested below what it matches, so "post:42"
+// covers "post:42:comments" and "post:*" covers "post:42:comments:7".
+// Tags containing glob metacharacters are therefore always treated as patterns.
+// find returns the existing tags matching a glob.
+func expandTags(patterns []string, find func(glob string) ([]string, error)) ([]string, error) {
+	seen := make(map[string]bool)
+	tags := make([]string, 0, len(patterns))
+	add := func(tag string) {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	for _, p := range patterns {
+		globs := []string{p + tagSeparator + "*"}
+		if isGlob(p) {
+			globs = append(globs, p)
+		} else {
+			add(p)
+		}
+
+		for _, g := range globs {
+			found, err := find(g)
+			if err != nil {
+				return nil, err
+			}
+			for _, tag := range found {
+				add(tag)
+			}
+		}
+	}
+	return tags, nil
+}
+
+// matchGlob reports whether s matches pattern using redis glob syntax.
+func matchGlob(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if matchGlob(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			n, ok := matchClass(pattern, s[0])
+			if !ok {
+				return false
+			}
+			pattern = pattern[n-1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+		}
+		pattern, s = pattern[1:], s[1:]
+	}
+	return len(s) == 0
+}
+
+// matchClass matches c against the character class at the start of pattern,
+// returning the length of the class including its brackets.
+func matchClass(pattern string, c byte) (int, bool) {
+	i := 1
+	negate := i < len(pattern) && pattern[i] == '^'
+	if negate {
+		i++
+	}
+
+	matched := false
+	for ; i < len(pattern) && pattern[i] != ']'; i++ {
+		lo := pattern[i]
+		if lo == '\\' && i+1 < len(pattern) {
+			i++
+			lo = pattern[i]
+		}
+		hi := lo
+		if i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']' {
+			hi = pattern[i+2]
+			i += 2
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+	}
+	if i == len(pattern) {
+		// an unterminated class matches like redis, up to the end of the pattern.
+		i--
+	}
+	return i + 1, matched != negate
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestMatchGlob checks matchGlob against redis glob semantics.
+func TestMatchGlob(t *testing.T) {
+	var tests = []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{pattern: "post:42", s: "post:42", want: true},
+		{pattern: "post:42", s: "post:421", want: false},
+		{pattern: "post:*", s: "post:42:comments", want: true},
+		{pattern: "post:*:comments", s: "post:42:comments", want: true},
+		{pattern: "post:*:comments", s: "post:42:likes", want: false},
+		{pattern: "post:4?", s: "post:42", want: true},
+		{pattern: "post:4?", s: "post:4", want: false},
+		{pattern: "post:[1-4]", s: "post:3", want: true},
+		{pattern: "post:[^1-4]", s: "post:3", want: false},
+		{pattern: "post:[ab]", s: "post:b", want: true},
+		{pattern: `post:\*`, s: "post:*", want: true},
+		{pattern: `post:\*`, s: "post:4", want: false},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when matching `%s` against `%s`", td.s, td.pattern)
+		t.Run(testname, func(t *testing.T) {
+			if got := matchGlob(td.pattern, td.s); got != td.want {
+				t.Errorf("got %t, want %t", got, td.want)
+			}
+		})
+	}
+}
+
+// TestHierarchicalInvalidate checks that invalidating a tag cascades to its
+// children and that globs select the matching tags only.
+func TestHierarchicalInvalidate(t *testing.T) {
+	for name, b := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			c := newCache(b)
+			sets := []struct{ key, tag string }{
+				{key: "data:post42", tag: "post:42"},
+				{key: "data:comments42", tag: "post:42:comments"},
+				{key: "data:likes42", tag: "post:42:likes"},
+				{key: "data:comments43", tag: "post:43:comments"},
+				{key: "data:post420", tag: "post:420"},
+			}
+			for _, s := range sets {
+				if err := c.SetByTags(ctx, s.key, "v", 0, []string{s.tag}); err != nil {
+					t.Fatalf("SetByTags(%s): %v", s.key, err)
+				}
+			}
+
+			if err := c.Invalidate(ctx, []string{"post:*:likes"}); err != nil {
+				t.Fatalf("Invalidate(post:*:likes): %v", err)
+			}
+			assertCached(t, c, "data:likes42", false)
+			assertCached(t, c, "data:comments42", true)
+
+			if err := c.Invalidate(ctx, []string{"post:42"}); err != nil {
+				t.Fatalf("Invalidate(post:42): %v", err)
+			}
+			assertCached(t, c, "data:post42", false)
+			assertCached(t, c, "data:comments42", false)
+			assertCached(t, c, "data:comments43", true)
+			assertCached(t, c, "data:post420", true)
+		})
+	}
+}
+
+func assertCached(t *testing.T, c *cache, key string, want bool) {
+	t.Helper()
+	_, err := c.Get(ctx, key)
+	if got := err == nil; got != want {
+		t.Errorf("%s cached = %t, want %t (err: %v)", key, got, want, err)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// fakeClock is a clock that only moves when told to.
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// TestTagSetExpiry checks that a tag set expires with its longest lived member,
+// and never when one of its members has no expiry.
+func TestTagSetExpiry(t *testing.T) {
+	clock := &fakeClock{t: time.Now()}
+	memory := newMemoryBackend()
+	memory.now = clock.Now
+
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	defer client.Close()
+
+	var tests = []struct {
+		name    string
+		backend Backend
+		advance func(time.Duration)
+	}{
+		{name: "memory", backend: memory, advance: clock.Advance},
+		{name: "redis", backend: newRedisBackend(client), advance: srv.FastForward},
+	}
+
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			b := td.backend
+			mustSetExpiry(t, b, "data:key1", "v", 10*time.Minute, []string{"post1"})
+			mustSetExpiry(t, b, "data:key2", "v", 30*time.Minute, []string{"post1", "post2"})
+			mustSetExpiry(t, b, "data:key3", "v", 20*time.Minute, []string{"post2"})
+			mustSetExpiry(t, b, "data:key4", "v", 0, []string{"post3"})
+			mustSetExpiry(t, b, "data:key5", "v", 5*time.Minute, []string{"post3", "post4"})
+
+			td.advance(15 * time.Minute)
+			assertTagKeys(t, b, "post1", 2)
+			assertTagKeys(t, b, "post4", 0)
+
+			td.advance(10 * time.Minute)
+			assertTagKeys(t, b, "post2", 2)
+
+			td.advance(10 * time.Minute)
+			assertTagKeys(t, b, "post1", 0)
+			assertTagKeys(t, b, "post2", 0)
+			assertTagKeys(t, b, "post3", 2)
+
+			// expired tags are no longer listed, and redis drops them from its index.
+			if tags, err := b.Tags(ctx, "*"); err != nil || !equal(unique(tags), []string{"post3"}) {
+				t.Errorf("Tags(*) = %v, %v, want [post3]", tags, err)
+			}
+			if r, ok := b.(*redisBackend); ok {
+				if n, err := client.SCard(ctx, r.indexKey()).Result(); err != nil || n != 1 {
+					t.Errorf("index holds %d tags, %v, want 1", n, err)
+				}
+			}
+		})
+	}
+}
+
+func assertTagKeys(t *testing.T, b Backend, tag string, want int) {
+	t.Helper()
+	keys, err := b.Keys(ctx, tag)
+	if err != nil {
+		t.Fatalf("Keys(%s): %v", tag, err)
+	}
+	if len(keys) != want {
+		t.Errorf("Keys(%s) = %v, want %d keys", tag, keys, want)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/abvarun226/checksum-validation-in-go/checksum"
+)
+
+// parseAlgorithms parses a comma separated list of algorithm names.
+func parseAlgorithms(list string) ([]checksum.Algorithm, error) {
+	algs := make([]checksum.Algorithm, 0)
+	for _, name := range strings.Split(list, ",") {
+		alg, err := checksum.ParseAlgorithm(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		algs = append(algs, alg)
+	}
+	return algs, nil
+}
+
+// openInput opens the named file, or standard input for "-".
+func openInput(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(name)
+}
+
+// checksumFile computes algs over the named file, or standard input for "-",
+// returning the hex encoded checksums in the order of algs.
+func checksumFile(ctx context.Context, name string, algs []checksum.Algorithm) ([]string, error) {
+	r, err := openInput(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	sums, err := checksum.Sum(ctx, r, algs...)
+	if err != nil {
+		return nil, err
+	}
+
+	hexSums := make([]string, len(algs))
+	for i, alg := range algs {
+		hexSums[i] = checksum.Hex(sums[alg])
+	}
+	return hexSums, nil
+}
@@ -0,0 +1,156 @@
+// Package checksum computes checksums of streams with several algorithms at once.
+package checksum
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Algorithm is a supported hash function.
+type Algorithm string
+
+// Supported algorithms.
+const (
+	MD5     Algorithm = "md5"
+	SHA1    Algorithm = "sha1"
+	SHA256  Algorithm = "sha256"
+	SHA512  Algorithm = "sha512"
+	BLAKE2b Algorithm = "blake2b" // BLAKE2b-512, the default of b2sum
+	CRC32   Algorithm = "crc32"   // IEEE polynomial
+)
+
+var (
+	// ErrUnknownAlgorithm is returned for algorithms that are not supported.
+	ErrUnknownAlgorithm = errors.New("unknown algorithm")
+)
+
+// algorithms maps every supported algorithm to its BSD tag and constructor,
+// in the order Algorithms returns them.
+var algorithms = []struct {
+	alg Algorithm
+	tag string
+	new func() hash.Hash
+}{
+	{alg: MD5, tag: "MD5", new: md5.New},
+	{alg: SHA1, tag: "SHA1", new: sha1.New},
+	{alg: SHA256, tag: "SHA256", new: sha256.New},
+	{alg: SHA512, tag: "SHA512", new: sha512.New},
+	{alg: BLAKE2b, tag: "BLAKE2b", new: newBlake2b},
+	{alg: CRC32, tag: "CRC32", new: func() hash.Hash { return crc32.NewIEEE() }},
+}
+
+// Algorithms returns every supported algorithm.
+func Algorithms() []Algorithm {
+	algs := make([]Algorithm, len(algorithms))
+	for i, a := range algorithms {
+		algs[i] = a.alg
+	}
+	return algs
+}
+
+// ParseAlgorithm returns the algorithm with the given name or BSD tag, ignoring case.
+func ParseAlgorithm(name string) (Algorithm, error) {
+	for _, a := range algorithms {
+		if strings.EqualFold(string(a.alg), name) || strings.EqualFold(a.tag, name) {
+			return a.alg, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q", ErrUnknownAlgorithm, name)
+}
+
+// New returns a new hash computing the algorithm, or nil if it is not supported.
+func (a Algorithm) New() hash.Hash {
+	for _, alg := range algorithms {
+		if alg.alg == a {
+			return alg.new()
+		}
+	}
+	return nil
+}
+
+// Tag returns the name of the algorithm in BSD tag format, e.g. "SHA256".
+func (a Algorithm) Tag() string {
+	for _, alg := range algorithms {
+		if alg.alg == a {
+			return alg.tag
+		}
+	}
+	return strings.ToUpper(string(a))
+}
+
+// Size returns the size of the checksums of the algorithm in bytes, 0 if it is not supported.
+func (a Algorithm) Size() int {
+	if h := a.New(); h != nil {
+		return h.Size()
+	}
+	return 0
+}
+
+// Sum reads r until EOF, computing every algorithm in a single pass.
+// It stops with the context's error as soon as ctx is done.
+func Sum(ctx context.Context, r io.Reader, algs ...Algorithm) (map[Algorithm][]byte, error) {
+	hashes := make(map[Algorithm]hash.Hash, len(algs))
+	writers := make([]io.Writer, 0, len(algs))
+	for _, alg := range algs {
+		if _, ok := hashes[alg]; ok {
+			continue
+		}
+		h := alg.New()
+		if h == nil {
+			return nil, fmt.Errorf("%w %q", ErrUnknownAlgorithm, alg)
+		}
+		hashes[alg] = h
+		writers = append(writers, h)
+	}
+
+	if _, err := io.Copy(io.MultiWriter(writers...), &contextReader{ctx: ctx, r: r}); err != nil {
+		return nil, err
+	}
+
+	sums := make(map[Algorithm][]byte, len(hashes))
+	for alg, h := range hashes {
+		sums[alg] = h.Sum(nil)
+	}
+	return sums, nil
+}
+
+// Hex encodes a checksum as lowercase hexadecimal, as printed by sha256sum.
+func Hex(sum []byte) string {
+	return hex.EncodeToString(sum)
+}
+
+// Base64 encodes a checksum as standard base64, as used by the Content-MD5 header.
+func Base64(sum []byte) string {
+	return base64.StdEncoding.EncodeToString(sum)
+}
+
+func newBlake2b() hash.Hash {
+	h, _ := blake2b.New512(nil) // only fails for keys that are too long.
+	return h
+}
+
+// contextReader is a reader that fails once its context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,51 @@
+package checksum
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestSum is the unit test to test Sum function.
+func TestSum(t *testing.T) {
+	var tests = []struct {
+		alg Algorithm
+		hex string
+		b64 string
+	}{
+		{alg: MD5, hex: "5d41402abc4b2a76b9719d911017c592", b64: "XUFAKrxLKna5cZ2REBfFkg=="},
+		{alg: SHA256, hex: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", b64: "LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ="},
+		{alg: CRC32, hex: "3610a686", b64: "NhCmhg=="},
+	}
+
+	sums, err := Sum(context.Background(), strings.NewReader("hello"), MD5, SHA256, CRC32)
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	for _, td := range tests {
+		testname := fmt.Sprintf("when testing %s", td.alg)
+		t.Run(testname, func(t *testing.T) {
+			if got := Hex(sums[td.alg]); got != td.hex {
+				t.Errorf("got %s, want %s", got, td.hex)
+			}
+			if got := Base64(sums[td.alg]); got != td.b64 {
+				t.Errorf("got %s, want %s", got, td.b64)
+			}
+		})
+	}
+}
+
+// TestSumErrors is the unit test to test errors returned by Sum function.
+func TestSumErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Sum(ctx, strings.NewReader("hello"), SHA256); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+
+	if _, err := Sum(context.Background(), strings.NewReader("hello"), "sha3"); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("got %v, want %v", err, ErrUnknownAlgorithm)
+	}
+}
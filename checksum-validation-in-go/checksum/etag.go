@@ -0,0 +1,77 @@
+package checksum
+
+import (
+	"crypto/md5"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+// ETag computes the ETag S3 assigns to an object: the MD5 of its content when it
+// was uploaded in a single part, or else the MD5 of the concatenated MD5s of its
+// parts followed by "-" and the number of parts. Objects encrypted with SSE-KMS
+// or SSE-C have ETags that are not derived from their content.
+type ETag struct {
+	partSize int64
+	part     hash.Hash
+	n        int64  // bytes written to the current part
+	sums     []byte // MD5s of the complete parts
+}
+
+// NewETag returns an ETag for uploads split in parts of partSize bytes,
+// as done by s3manager.Uploader with the same PartSize.
+func NewETag(partSize int64) *ETag {
+	return &ETag{partSize: partSize, part: md5.New()}
+}
+
+// Write adds p to the content of the object.
+func (e *ETag) Write(p []byte) (int, error) {
+	written := len(p)
+	for len(p) > 0 {
+		chunk := p
+		if e.partSize > 0 && int64(len(chunk)) > e.partSize-e.n {
+			chunk = chunk[:e.partSize-e.n]
+		}
+		e.part.Write(chunk)
+		e.n += int64(len(chunk))
+		p = p[len(chunk):]
+
+		if e.n == e.partSize {
+			e.sums = e.part.Sum(e.sums)
+			e.part.Reset()
+			e.n = 0
+		}
+	}
+	return written, nil
+}
+
+// String returns the ETag of the content written so far, without quotes.
+func (e *ETag) String() string {
+	sums := e.sums
+	if e.n > 0 || len(sums) == 0 {
+		sums = e.part.Sum(sums)
+	}
+
+	parts := len(sums) / md5.Size
+	if parts == 1 {
+		return Hex(sums)
+	}
+	sum := md5.Sum(sums)
+	return fmt.Sprintf("%s-%d", Hex(sum[:]), parts)
+}
+
+// ParseETag strips the quotes S3 puts around an ETag and returns the number
+// of parts the object was uploaded in, 0 for objects uploaded in a single part.
+func ParseETag(etag string) (string, int) {
+	etag = strings.ToLower(strings.Trim(etag, `"`))
+	i := strings.LastIndexByte(etag, '-')
+	if i < 0 {
+		return etag, 0
+	}
+	parts, err := strconv.Atoi(etag[i+1:])
+	if err != nil {
+		return etag, 0
+	}
+	return etag, parts
+}
@@ -0,0 +1,61 @@
+package checksum
+
+import (
+	"crypto/md5"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestETag is the unit test to test ETag type.
+func TestETag(t *testing.T) {
+	md5Hex := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return Hex(sum[:])
+	}
+	multipart := func(parts ...string) string {
+		var sums []byte
+		for _, p := range parts {
+			sum := md5.Sum([]byte(p))
+			sums = append(sums, sum[:]...)
+		}
+		sum := md5.Sum(sums)
+		return fmt.Sprintf("%s-%d", Hex(sum[:]), len(parts))
+	}
+
+	var tests = []struct {
+		content  string
+		partSize int64
+		want     string
+	}{
+		{content: "", partSize: 5, want: md5Hex("")},
+		{content: "hello", partSize: 5, want: md5Hex("hello")},
+		{content: "hello", partSize: 8, want: md5Hex("hello")},
+		{content: "helloworld", partSize: 5, want: multipart("hello", "world")},
+		{content: "hello world", partSize: 5, want: multipart("hello", " worl", "d")},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when testing %q in parts of %d bytes", td.content, td.partSize)
+		t.Run(testname, func(t *testing.T) {
+			etag := NewETag(td.partSize)
+			// write a byte at a time and then all at once to cross part boundaries.
+			for _, c := range []byte(td.content) {
+				etag.Write([]byte{c})
+			}
+			if got := etag.String(); got != td.want {
+				t.Errorf("got %s, want %s", got, td.want)
+			}
+
+			etag = NewETag(td.partSize)
+			etag.Write([]byte(td.content))
+			if got := etag.String(); got != td.want {
+				t.Errorf("got %s, want %s", got, td.want)
+			}
+
+			if got, _ := ParseETag(`"` + strings.ToUpper(td.want) + `"`); got != td.want {
+				t.Errorf("got %s, want %s", got, td.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package checksum
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/textproto"
+	"strings"
+)
+
+// ErrMismatch is matched by the errors returned when data does not have
+// the expected checksum.
+var ErrMismatch = errors.New("checksum mismatch")
+
+// MismatchError reports the checksum found for data that was expected to have another.
+type MismatchError struct {
+	Algorithm Algorithm
+	Want      []byte
+	Got       []byte
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("%s checksum mismatch: got %s, want %s", e.Algorithm, Hex(e.Got), Hex(e.Want))
+}
+
+// Unwrap returns ErrMismatch.
+func (e *MismatchError) Unwrap() error {
+	return ErrMismatch
+}
+
+// VerifyingReader hashes the data read from an underlying reader and fails
+// at the end of it if the checksum is not the expected one.
+type VerifyingReader struct {
+	r    io.Reader
+	alg  Algorithm
+	h    hash.Hash
+	want []byte
+}
+
+// NewVerifyingReader returns a reader reading r, whose final read returns a
+// *MismatchError instead of io.EOF if the alg checksum of the data is not want.
+// Callers must not trust the data until they have read io.EOF.
+func NewVerifyingReader(r io.Reader, alg Algorithm, want []byte) *VerifyingReader {
+	return &VerifyingReader{r: r, alg: alg, h: alg.New(), want: want}
+}
+
+func (v *VerifyingReader) Read(p []byte) (int, error) {
+	if v.h == nil {
+		return 0, fmt.Errorf("%w %q", ErrUnknownAlgorithm, v.alg)
+	}
+
+	n, err := v.r.Read(p)
+	v.h.Write(p[:n])
+	if err == io.EOF {
+		if got := v.h.Sum(nil); !bytes.Equal(got, v.want) {
+			return n, &MismatchError{Algorithm: v.alg, Want: v.want, Got: got}
+		}
+	}
+	return n, err
+}
+
+// digestAlgorithms maps the RFC 3230 names of supported algorithms to them,
+// strongest first.
+var digestAlgorithms = []struct {
+	name string
+	alg  Algorithm
+}{
+	{name: "SHA-512", alg: SHA512},
+	{name: "SHA-256", alg: SHA256},
+	{name: "SHA", alg: SHA1},
+	{name: "MD5", alg: MD5},
+}
+
+// DigestFromHeader returns the checksum announced by the Digest header (RFC 3230)
+// of h, using the strongest supported algorithm, or else by its Content-MD5 header.
+// It returns an empty algorithm when h announces no checksum.
+func DigestFromHeader(h textproto.MIMEHeader) (Algorithm, []byte, error) {
+	if digest := h.Get("Digest"); digest != "" {
+		sums := make(map[string]string)
+		for _, d := range strings.Split(digest, ",") {
+			i := strings.IndexByte(d, '=')
+			if i < 0 {
+				return "", nil, fmt.Errorf("malformed Digest header %q", digest)
+			}
+			sums[strings.ToUpper(strings.TrimSpace(d[:i]))] = strings.TrimSpace(d[i+1:])
+		}
+
+		for _, d := range digestAlgorithms {
+			if value, ok := sums[d.name]; ok {
+				return decodeDigest(d.alg, value)
+			}
+		}
+		return "", nil, fmt.Errorf("%w in Digest header %q", ErrUnknownAlgorithm, digest)
+	}
+
+	if value := h.Get("Content-MD5"); value != "" {
+		return decodeDigest(MD5, value)
+	}
+	return "", nil, nil
+}
+
+// decodeDigest decodes a base64 checksum computed with alg.
+func decodeDigest(alg Algorithm, value string) (Algorithm, []byte, error) {
+	sum, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(sum) != alg.Size() {
+		return "", nil, fmt.Errorf("malformed %s checksum %q", alg, value)
+	}
+	return alg, sum, nil
+}
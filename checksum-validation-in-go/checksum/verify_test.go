@@ -0,0 +1,83 @@
+package checksum
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// TestVerifyingReader is the unit test to test VerifyingReader type.
+func TestVerifyingReader(t *testing.T) {
+	md5Sum, _ := hex.DecodeString("5d41402abc4b2a76b9719d911017c592")
+
+	var tests = []struct {
+		content string
+		alg     Algorithm
+		wantErr error
+	}{
+		{content: "hello", alg: MD5},
+		{content: "hallo", alg: MD5, wantErr: ErrMismatch},
+		{content: "hello", alg: "sha3", wantErr: ErrUnknownAlgorithm},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when reading %q with %s", td.content, td.alg)
+		t.Run(testname, func(t *testing.T) {
+			data, err := ioutil.ReadAll(NewVerifyingReader(strings.NewReader(td.content), td.alg, md5Sum))
+			if !errors.Is(err, td.wantErr) {
+				t.Fatalf("got error %v, want %v", err, td.wantErr)
+			}
+			if err == nil && string(data) != td.content {
+				t.Errorf("got %q, want %q", data, td.content)
+			}
+		})
+	}
+}
+
+// TestDigestFromHeader is the unit test to test DigestFromHeader function.
+func TestDigestFromHeader(t *testing.T) {
+	md5B64 := "XUFAKrxLKna5cZ2REBfFkg=="
+	sha256B64 := "LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ="
+
+	var tests = []struct {
+		header  map[string]string
+		alg     Algorithm
+		wantErr bool
+	}{
+		{header: map[string]string{}},
+		{header: map[string]string{"Content-MD5": md5B64}, alg: MD5},
+		{header: map[string]string{"Digest": "MD5=" + md5B64 + ", SHA-256=" + sha256B64}, alg: SHA256},
+		{header: map[string]string{"Digest": "sha-256=" + sha256B64, "Content-MD5": md5B64}, alg: SHA256},
+		{header: map[string]string{"Digest": "UNIXsum=30637"}, wantErr: true},
+		{header: map[string]string{"Digest": "SHA-256=" + md5B64}, wantErr: true},
+		{header: map[string]string{"Content-MD5": "not base64"}, wantErr: true},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when testing %v", td.header)
+		t.Run(testname, func(t *testing.T) {
+			h := make(textproto.MIMEHeader)
+			for k, v := range td.header {
+				h.Set(k, v)
+			}
+
+			alg, sum, err := DigestFromHeader(h)
+			if td.wantErr {
+				if err == nil {
+					t.Errorf("got %s %x, want an error", alg, sum)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if alg != td.alg || (alg != "" && len(sum) != alg.Size()) {
+				t.Errorf("got %s %x, want a %s checksum", alg, sum, td.alg)
+			}
+		})
+	}
+}
@@ -1,3 +1,8 @@
 module github.com/abvarun226/checksum-validation-in-go
 
 go 1.16
+
+require (
+	github.com/aws/aws-sdk-go v1.42.23
+	golang.org/x/crypto v0.0.0-20220525230936-793ad666bf5e
+)
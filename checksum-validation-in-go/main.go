@@ -1,39 +1,178 @@
 package main
 
 import (
-	"crypto/md5"
-	"crypto/sha256"
+	"context"
+	"flag"
 	"fmt"
 	"io"
-	"log"
 	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/abvarun226/checksum-validation-in-go/checksum"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
 func main() {
-	f, err := os.Open("secret.txt")
+	algList := flag.String("a", "sha256", "comma separated algorithms: md5, sha1, sha256, sha512, blake2b, crc32")
+	tag := flag.Bool("tag", false, "print BSD style checksums, the default when several algorithms are given")
+	check := flag.Bool("c", false, "read checksums from the files and check them")
+	recursive := flag.Bool("r", false, "hash directories recursively, printing every file and the digest of the tree")
+	workers := flag.Int("j", runtime.NumCPU(), "number of files hashed concurrently with -r")
+	s3URL := flag.String("s3", "", "check the files against their objects at s3://bucket/key, or under s3://bucket/prefix/")
+	partSize := flag.Int64("part-size", s3manager.DefaultUploadPartSize, "part size in bytes of multipart uploads, to compute ETags with -s3")
+	endpoint := flag.String("endpoint", "", "S3 endpoint to use with -s3, e.g. http://127.0.0.1:4566 for localstack")
+	region := flag.String("region", "us-west-2", "AWS region to use with -s3")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [file ...]\n\nWith no file, or when file is -, read standard input.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	algs, err := parseAlgorithms(*algList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		stop()
+		os.Exit(2)
+	}
+
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	if *s3URL != "" {
+		status := verifyS3Objects(ctx, *s3URL, files, *partSize, *endpoint, *region)
+		stop()
+		os.Exit(status)
+	}
+
+	if *check {
+		status := verifyManifests(ctx, files, manifestAlgorithm(algs), *workers)
+		stop()
+		os.Exit(status)
+	}
+
+	p := printer{out: os.Stdout, algs: algs, tag: *tag || len(algs) > 1}
+	status := 0
+	for _, name := range files {
+		if *recursive && isDir(name) {
+			if err := p.printTree(ctx, name, *workers); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+				status = 1
+			}
+			continue
+		}
+
+		sums, err := checksumFile(ctx, name, algs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			status = 1
+			continue
+		}
+		p.print(name, sums)
+	}
+	stop()
+	os.Exit(status)
+}
+
+// verifyS3Objects checks files against their S3 objects, warning about
+// failures on standard error. It returns the exit status.
+func verifyS3Objects(ctx context.Context, rawURL string, files []string, partSize int64, endpoint, region string) int {
+	loc, err := parseS3URL(rawURL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if len(files) > 1 && loc.key != "" && !strings.HasSuffix(loc.key, "/") {
+		fmt.Fprintf(os.Stderr, "%s: a prefix ending with / is needed to check several files\n", rawURL)
+		return 2
+	}
+
+	client, err := newS3Client(endpoint, region)
 	if err != nil {
-		log.Fatal(err)
+		fmt.Fprintf(os.Stderr, "failed to create a new aws session: %v\n", err)
+		return 1
 	}
-	defer f.Close()
 
-	fmt.Printf("md5 checksum: %s\n", checksumMD5(f))
-	fmt.Printf("sha256 checksum: %s\n", checksumSHA256(f))
+	res := verifyS3(ctx, client, loc, files, partSize, os.Stdout)
+	warn(rawURL, res.missing, "object is missing", "objects are missing")
+	warn(rawURL, res.failed, "computed checksum did NOT match", "computed checksums did NOT match")
+	if !res.success() {
+		return 1
+	}
+	return 0
+}
+
+// printer prints checksums to out in GNU coreutils or BSD tag format.
+type printer struct {
+	out  io.Writer
+	algs []checksum.Algorithm
+	tag  bool
+}
+
+// print prints the checksums of name, one line per algorithm.
+func (p printer) print(name string, sums []string) {
+	for i, alg := range p.algs {
+		if p.tag {
+			fmt.Fprintf(p.out, "%s (%s) = %s\n", alg.Tag(), name, sums[i])
+		} else {
+			fmt.Fprintln(p.out, formatLine(sums[i], name))
+		}
+	}
 }
 
-func checksumMD5(f io.Reader) string {
-	h := md5.New()
-	if _, err := io.Copy(h, f); err != nil {
-		log.Fatal(err)
+// printTree prints the checksums of every file under dir, followed by the
+// digest of the tree under dir's name with a trailing slash.
+func (p printer) printTree(ctx context.Context, dir string, workers int) error {
+	tree, err := hashTree(ctx, dir, p.algs, workers)
+	if err != nil {
+		return err
 	}
 
-	return fmt.Sprintf("%x", h.Sum(nil))
+	for _, f := range tree.files {
+		p.print(path.Join(filepath.ToSlash(dir), f.path), f.sums)
+	}
+	p.print(treeName(dir), tree.sums)
+	return nil
+}
+
+// treeName returns the name under which the digest of the tree dir is printed.
+func treeName(dir string) string {
+	return strings.TrimSuffix(filepath.ToSlash(dir), "/") + "/"
+}
+
+func isDir(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && fi.IsDir()
+}
+
+// manifestAlgorithm returns the algorithm of GNU format manifest lines: the one given
+// with -a, or "" to guess it from each checksum when -a was not set.
+func manifestAlgorithm(algs []checksum.Algorithm) checksum.Algorithm {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		set = set || f.Name == "a"
+	})
+	if !set || len(algs) != 1 {
+		return ""
+	}
+	return algs[0]
 }
 
-func checksumSHA256(f io.Reader) string {
-	h := sha256.New()
-	if _, err := io.Copy(h, f); err != nil {
-		log.Fatal(err)
+// formatLine formats a checksum line the way GNU coreutils does, escaping
+// backslashes and newlines in name and flagging such lines with a leading backslash.
+func formatLine(sum, name string) string {
+	if !strings.ContainsAny(name, "\\\n") {
+		return sum + "  " + name
 	}
 
-	return fmt.Sprintf("%x", h.Sum(nil))
+	name = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(name)
+	return `\` + sum + "  " + name
 }
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/abvarun226/checksum-validation-in-go/checksum"
+)
+
+// TestPrint is the unit test to test printer print function.
+func TestPrint(t *testing.T) {
+	sha256Sum := "9e52d720c78aeb0411da37c1d25c8950485586ca392da33f2961da8caad91169"
+	md5Sum := "408818265c03bda82e531d6f575183d7"
+
+	var tests = []struct {
+		algs []checksum.Algorithm
+		sums []string
+		tag  bool
+		name string
+		want string
+	}{
+		{algs: []checksum.Algorithm{checksum.SHA256}, sums: []string{sha256Sum}, name: "secret.txt", want: sha256Sum + "  secret.txt\n"},
+		{algs: []checksum.Algorithm{checksum.SHA256}, sums: []string{sha256Sum}, name: "two\nlines.txt", want: `\` + sha256Sum + `  two\nlines.txt` + "\n"},
+		{algs: []checksum.Algorithm{checksum.SHA256}, sums: []string{sha256Sum}, name: `back\slash.txt`, want: `\` + sha256Sum + `  back\\slash.txt` + "\n"},
+		{algs: []checksum.Algorithm{checksum.SHA256}, sums: []string{sha256Sum}, tag: true, name: "secret.txt", want: "SHA256 (secret.txt) = " + sha256Sum + "\n"},
+		{
+			algs: []checksum.Algorithm{checksum.SHA256, checksum.MD5},
+			sums: []string{sha256Sum, md5Sum},
+			tag:  true,
+			name: "a (1).txt",
+			want: "SHA256 (a (1).txt) = " + sha256Sum + "\nMD5 (a (1).txt) = " + md5Sum + "\n",
+		},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when printing %q with tag %t", td.name, td.tag)
+		t.Run(testname, func(t *testing.T) {
+			var out bytes.Buffer
+			p := printer{out: &out, algs: td.algs, tag: td.tag}
+			p.print(td.name, td.sums)
+			if got := out.String(); got != td.want {
+				t.Errorf("got %q, want %q", got, td.want)
+			}
+		})
+	}
+}
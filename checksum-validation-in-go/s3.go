@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/abvarun226/checksum-validation-in-go/checksum"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// sha256MetadataKey is the user metadata, sent as x-amz-meta-sha256, holding
+// the hex SHA-256 of an object.
+const sha256MetadataKey = "sha256"
+
+// s3Location is a bucket and key, or key prefix when it ends with a slash.
+type s3Location struct {
+	bucket string
+	key    string
+}
+
+// parseS3URL parses an s3://bucket/key URL.
+func parseS3URL(s string) (s3Location, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s3Location{}, err
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return s3Location{}, fmt.Errorf("%q is not an s3://bucket/key URL", s)
+	}
+	return s3Location{bucket: u.Host, key: strings.TrimPrefix(u.Path, "/")}, nil
+}
+
+// objectKey returns the key of the object holding the local file name: the
+// location's key itself, or name appended to it when the key is a prefix.
+func (l s3Location) objectKey(name string) string {
+	if l.key != "" && !strings.HasSuffix(l.key, "/") {
+		return l.key
+	}
+	return l.key + strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "/")
+}
+
+// newS3Client returns an S3 client for region, talking to endpoint when it is
+// set, e.g. http://127.0.0.1:4566 for localstack.
+func newS3Client(endpoint, region string) (s3iface.S3API, error) {
+	conf := aws.NewConfig().WithRegion(region)
+	if endpoint != "" {
+		conf = conf.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+	}
+
+	sess, err := session.NewSession(conf)
+	if err != nil {
+		return nil, err
+	}
+	return s3.New(sess), nil
+}
+
+// verifyS3 checks every named file, or standard input for "-", against its
+// object under loc, printing OK, FAILED or MISSING for each of them to out.
+// The ETag is recomputed for uploads in parts of partSize bytes, and the SHA-256
+// is compared with the x-amz-meta-sha256 metadata when the object has it.
+func verifyS3(ctx context.Context, client s3iface.S3API, loc s3Location, names []string, partSize int64, out io.Writer) verifyResult {
+	var res verifyResult
+	for _, name := range names {
+		key := loc.objectKey(name)
+		head, err := client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(loc.bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			var aerr awserr.Error
+			if errors.As(err, &aerr) && aerr.Code() == "NotFound" {
+				res.missing++
+				fmt.Fprintf(out, "%s: MISSING s3://%s/%s\n", name, loc.bucket, key)
+				continue
+			}
+			res.failed++
+			fmt.Fprintf(out, "%s: FAILED head object: %v\n", name, err)
+			continue
+		}
+
+		etag, sha, err := checksumObject(ctx, name, partSize)
+		if err != nil {
+			res.failed++
+			fmt.Fprintf(out, "%s: FAILED open or read\n", name)
+			continue
+		}
+
+		var mismatches []string
+		if remote, parts := checksum.ParseETag(aws.StringValue(head.ETag)); remote != etag {
+			mismatch := "etag"
+			if _, local := checksum.ParseETag(etag); parts != local {
+				mismatch = fmt.Sprintf("etag (%d parts, %d expected, check the part size)", partCount(parts), partCount(local))
+			}
+			mismatches = append(mismatches, mismatch)
+		}
+		if remote, ok := metadata(head.Metadata, sha256MetadataKey); ok && !strings.EqualFold(remote, sha) {
+			mismatches = append(mismatches, "sha256")
+		}
+
+		if len(mismatches) > 0 {
+			res.failed++
+			fmt.Fprintf(out, "%s: FAILED %s\n", name, strings.Join(mismatches, ", "))
+			continue
+		}
+		res.ok++
+		fmt.Fprintf(out, "%s: OK\n", name)
+	}
+	return res
+}
+
+// partCount returns the number of parts of an upload given by ParseETag.
+func partCount(parts int) int {
+	if parts == 0 {
+		return 1
+	}
+	return parts
+}
+
+// checksumObject computes the S3 ETag and the hex SHA-256 of the named file,
+// or standard input for "-", in a single pass.
+func checksumObject(ctx context.Context, name string, partSize int64) (string, string, error) {
+	r, err := openInput(name)
+	if err != nil {
+		return "", "", err
+	}
+	defer r.Close()
+
+	etag := checksum.NewETag(partSize)
+	sums, err := checksum.Sum(ctx, io.TeeReader(r, etag), checksum.SHA256)
+	if err != nil {
+		return "", "", err
+	}
+	return etag.String(), checksum.Hex(sums[checksum.SHA256]), nil
+}
+
+// metadata looks up user metadata ignoring case, since S3 returns
+// the keys in canonical header form, e.g. "Sha256".
+func metadata(m map[string]*string, key string) (string, bool) {
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return aws.StringValue(v), true
+		}
+	}
+	return "", false
+}
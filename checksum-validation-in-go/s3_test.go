@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// fakeS3 serves HeadObject from a map of keys to objects.
+type fakeS3 struct {
+	s3iface.S3API
+	objects map[string]*s3.HeadObjectOutput
+}
+
+func (f fakeS3) HeadObjectWithContext(ctx aws.Context, in *s3.HeadObjectInput, opts ...request.Option) (*s3.HeadObjectOutput, error) {
+	out, ok := f.objects[aws.StringValue(in.Bucket)+"/"+aws.StringValue(in.Key)]
+	if !ok {
+		return nil, awserr.NewRequestFailure(awserr.New("NotFound", "Not Found", nil), 404, "")
+	}
+	return out, nil
+}
+
+// TestVerifyS3 is the unit test to test verifyS3 function.
+func TestVerifyS3(t *testing.T) {
+	dir := t.TempDir()
+	content := "this is file 1"
+	name := filepath.Join(dir, "file1.txt")
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sha := "603754029ca9660505d1a1c573ba0c30ef6d1a828f770400b68debe8db843545"
+
+	// ETags of "this is file 1" uploaded whole and in parts of 5 bytes.
+	single := `"b063ecf2fe33d985f1b5cbfca5fc558c"`
+	multipart := `"3929614a67dfa89b7920aa8f4b655a93-3"`
+
+	var tests = []struct {
+		object   *s3.HeadObjectOutput
+		partSize int64
+		want     string
+	}{
+		{object: &s3.HeadObjectOutput{ETag: aws.String(single)}, partSize: 1 << 20, want: "OK"},
+		{object: &s3.HeadObjectOutput{ETag: aws.String(multipart)}, partSize: 5, want: "OK"},
+		{object: &s3.HeadObjectOutput{ETag: aws.String(single), Metadata: map[string]*string{"Sha256": aws.String(sha)}}, partSize: 1 << 20, want: "OK"},
+		{object: &s3.HeadObjectOutput{ETag: aws.String(single), Metadata: map[string]*string{"Sha256": aws.String("00")}}, partSize: 1 << 20, want: "FAILED sha256"},
+		{object: &s3.HeadObjectOutput{ETag: aws.String(`"00000000000000000000000000000000"`)}, partSize: 1 << 20, want: "FAILED etag"},
+		{object: &s3.HeadObjectOutput{ETag: aws.String(multipart)}, partSize: 1 << 20, want: "FAILED etag (3 parts, 1 expected, check the part size)"},
+		{object: nil, partSize: 1 << 20, want: "MISSING s3://work-with-s3/blog/file1.txt"},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when testing %s", td.want)
+		t.Run(testname, func(t *testing.T) {
+			client := fakeS3{objects: map[string]*s3.HeadObjectOutput{}}
+			if td.object != nil {
+				client.objects["work-with-s3/blog/file1.txt"] = td.object
+			}
+			loc := s3Location{bucket: "work-with-s3", key: "blog/file1.txt"}
+
+			var out bytes.Buffer
+			verifyS3(context.Background(), client, loc, []string{name}, td.partSize, &out)
+			if got, want := out.String(), name+": "+td.want+"\n"; got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+		})
+	}
+}
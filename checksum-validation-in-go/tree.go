@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/abvarun226/checksum-validation-in-go/checksum"
+)
+
+// fileDigest holds the checksums of a file found under a tree.
+type fileDigest struct {
+	path string // slash separated, relative to the root of the tree
+	sums []string
+	err  error
+}
+
+// treeDigest holds the checksums of every file under a directory along with
+// the digest of the whole tree, one per algorithm.
+type treeDigest struct {
+	files []fileDigest // sorted by path
+	sums  []string
+}
+
+// hashTree walks the directory root, hashing its regular files with up to workers
+// files at a time. Symbolic links and other special files are skipped.
+//
+// The tree digest is a Merkle tree following the directory layout: a file node
+// hashes "f", its name and its checksum, a directory node hashes "d", its name and
+// the nodes of its entries sorted by name, and the tree digest is the root's node
+// with an empty name. It only depends on relative paths and file contents, so
+// two copies of a tree have the same digest wherever they live.
+func hashTree(ctx context.Context, root string, algs []checksum.Algorithm, workers int) (treeDigest, error) {
+	var files, dirs []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		switch {
+		case d.IsDir():
+			dirs = append(dirs, rel)
+		case d.Type().IsRegular():
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return treeDigest{}, err
+	}
+
+	digests := hashFiles(ctx, root, files, algs, workers)
+	for _, d := range digests {
+		if d.err != nil {
+			return treeDigest{files: digests}, fmt.Errorf("%s: %w", d.path, d.err)
+		}
+	}
+
+	sums := make([]string, len(algs))
+	for i, alg := range algs {
+		sums[i] = merkleRoot(alg, dirs, digests, i)
+	}
+	return treeDigest{files: digests, sums: sums}, nil
+}
+
+// hashFiles checksums files, relative to root, using a pool of workers.
+// The digests are returned sorted by path.
+func hashFiles(ctx context.Context, root string, files []string, algs []checksum.Algorithm, workers int) []fileDigest {
+	if workers < 1 {
+		workers = 1
+	}
+	sort.Strings(files)
+	digests := make([]fileDigest, len(files))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				sums, err := checksumFile(ctx, filepath.Join(root, filepath.FromSlash(files[i])), algs)
+				digests[i] = fileDigest{path: files[i], sums: sums, err: err}
+			}
+		}()
+	}
+
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return digests
+}
+
+// merkleRoot computes the tree digest for algs[i] out of the directories and
+// file digests of a tree.
+func merkleRoot(alg checksum.Algorithm, dirs []string, files []fileDigest, i int) string {
+	children := make(map[string][]string)
+	nodes := make(map[string]string)
+	for _, f := range files {
+		nodes[f.path] = node(alg, "f", path.Base(f.path), f.sums[i])
+		parent := path.Dir(f.path)
+		children[parent] = append(children[parent], f.path)
+	}
+	for _, d := range dirs {
+		if d != "." {
+			parent := path.Dir(d)
+			children[parent] = append(children[parent], d)
+		}
+	}
+
+	// sorting deepest first guarantees children are hashed before their parent.
+	sorted := append([]string(nil), dirs...)
+	sort.Slice(sorted, func(a, b int) bool {
+		return depth(sorted[a]) > depth(sorted[b])
+	})
+	for _, d := range sorted {
+		entries := children[d]
+		sort.Strings(entries)
+
+		var b strings.Builder
+		for _, e := range entries {
+			b.WriteString(nodes[e])
+		}
+		name := path.Base(d)
+		if d == "." {
+			name = ""
+		}
+		nodes[d] = node(alg, "d", name, b.String())
+	}
+	return nodes["."]
+}
+
+// node hashes a Merkle tree node of the given kind.
+func node(alg checksum.Algorithm, kind, name, content string) string {
+	h := alg.New()
+	fmt.Fprintf(h, "%s%d:%s%s", kind, len(name), name, content)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func depth(p string) int {
+	if p == "." {
+		return 0
+	}
+	return strings.Count(p, "/") + 1
+}
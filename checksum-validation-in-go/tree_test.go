@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abvarun226/checksum-validation-in-go/checksum"
+)
+
+// TestTreeDigest checks that the tree digest only depends on relative paths and contents.
+func TestTreeDigest(t *testing.T) {
+	files := map[string]string{
+		"top":       "3",
+		"a/one":     "1",
+		"a/b/two":   "2",
+		"empty/":    "",
+		"c/d/three": "3",
+	}
+
+	var tests = []struct {
+		name   string
+		change func(dir string)
+		same   bool
+	}{
+		{name: "identical copy", change: func(string) {}, same: true},
+		{name: "changed content", change: func(dir string) { write(t, dir, "a/one", "changed") }},
+		{name: "renamed file", change: func(dir string) {
+			os.Rename(filepath.Join(dir, "top"), filepath.Join(dir, "top2"))
+		}},
+		{name: "moved file", change: func(dir string) {
+			os.Rename(filepath.Join(dir, "a/b/two"), filepath.Join(dir, "a/two"))
+		}},
+		{name: "added empty directory", change: func(dir string) { write(t, dir, "empty2/", "") }},
+	}
+
+	algs, _ := parseAlgorithms("sha256,md5")
+	want := tree(t, files, func(string) {}, algs)
+	for _, td := range tests {
+		t.Run(td.name, func(t *testing.T) {
+			got := tree(t, files, td.change, algs)
+			for i := range algs {
+				if same := got.sums[i] == want.sums[i]; same != td.same {
+					t.Errorf("%s digests equal = %t, want %t", algs[i], same, td.same)
+				}
+			}
+		})
+	}
+}
+
+// tree creates files in a temporary directory, applies change and hashes the tree.
+func tree(t *testing.T, files map[string]string, change func(dir string), algs []checksum.Algorithm) treeDigest {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		write(t, dir, name, content)
+	}
+	change(dir)
+
+	digest, err := hashTree(context.Background(), dir, algs, 4)
+	if err != nil {
+		t.Fatalf("hashTree: %v", err)
+	}
+	return digest
+}
+
+// write creates the named file with content, or a directory for names ending with a slash.
+func write(t *testing.T, dir, name, content string) {
+	t.Helper()
+	p := filepath.Join(dir, filepath.FromSlash(name))
+	if name[len(name)-1] == '/' {
+		if err := os.MkdirAll(p, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
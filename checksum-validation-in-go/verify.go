@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/abvarun226/checksum-validation-in-go/checksum"
+)
+
+// manifestEntry is a line of a checksum manifest.
+type manifestEntry struct {
+	alg  checksum.Algorithm
+	sum  string
+	name string
+}
+
+// verifyResult counts the outcomes of a manifest verification.
+type verifyResult struct {
+	ok        int
+	failed    int
+	missing   int
+	malformed int
+}
+
+// success reports whether every listed file was found and matched.
+func (r verifyResult) success() bool {
+	return r.failed == 0 && r.missing == 0 && r.ok > 0
+}
+
+// parseManifestLine parses a line in GNU coreutils format ("<sum>  <name>", or
+// "<sum> *<name>" for binary mode) or BSD tag format ("SHA256 (<name>) = <sum>").
+// GNU lines carry no algorithm name, so it is taken from def, or else guessed
+// from the length of the sum when def is empty.
+func parseManifestLine(line string, def checksum.Algorithm) (manifestEntry, error) {
+	if alg, rest, ok := cutTag(line); ok {
+		i := strings.LastIndex(rest, ") = ")
+		if !strings.HasPrefix(rest, " (") || i < 0 {
+			return manifestEntry{}, errors.New("improperly formatted tag line")
+		}
+		return manifestEntry{alg: alg, name: rest[2:i], sum: strings.ToLower(rest[i+4:])}, nil
+	}
+
+	escaped := strings.HasPrefix(line, `\`)
+	if escaped {
+		line = line[1:]
+	}
+
+	i := strings.IndexByte(line, ' ')
+	if i < 0 || i+2 > len(line) || (line[i+1] != ' ' && line[i+1] != '*') {
+		return manifestEntry{}, errors.New("improperly formatted line")
+	}
+	sum, name := strings.ToLower(line[:i]), line[i+2:]
+	if escaped {
+		name = strings.NewReplacer(`\\`, `\`, `\n`, "\n").Replace(name)
+	}
+
+	if def != "" {
+		return manifestEntry{alg: def, sum: sum, name: name}, nil
+	}
+	alg, ok := algorithmForLength(len(sum))
+	if !ok {
+		return manifestEntry{}, fmt.Errorf("cannot tell the algorithm of a %d character checksum", len(sum))
+	}
+	return manifestEntry{alg: alg, sum: sum, name: name}, nil
+}
+
+// cutTag splits the algorithm tag off a BSD tag format line.
+func cutTag(line string) (checksum.Algorithm, string, bool) {
+	i := strings.Index(line, " (")
+	if i < 0 {
+		return "", "", false
+	}
+	alg, err := checksum.ParseAlgorithm(line[:i])
+	return alg, line[i:], err == nil
+}
+
+// algorithmForLength returns the only algorithm producing hex sums of length n.
+func algorithmForLength(n int) (checksum.Algorithm, bool) {
+	var found []checksum.Algorithm
+	for _, alg := range checksum.Algorithms() {
+		if alg.Size()*2 == n {
+			found = append(found, alg)
+		}
+	}
+	if len(found) != 1 {
+		return "", false
+	}
+	return found[0], true
+}
+
+// verifyManifest checks every file listed in the manifest read from r,
+// printing OK, FAILED or MISSING for each of them to out. Names ending with
+// a slash are directories checked against their tree digest, see hashTree.
+func verifyManifest(ctx context.Context, r io.Reader, def checksum.Algorithm, workers int, out io.Writer) (verifyResult, error) {
+	var res verifyResult
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry, err := parseManifestLine(line, def)
+		if err != nil {
+			res.malformed++
+			continue
+		}
+
+		var sums []string
+		if strings.HasSuffix(entry.name, "/") {
+			var tree treeDigest
+			tree, err = hashTree(ctx, entry.name, []checksum.Algorithm{entry.alg}, workers)
+			sums = tree.sums
+		} else {
+			sums, err = checksumFile(ctx, entry.name, []checksum.Algorithm{entry.alg})
+		}
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			res.missing++
+			fmt.Fprintf(out, "%s: MISSING\n", entry.name)
+		case err != nil:
+			res.failed++
+			fmt.Fprintf(out, "%s: FAILED open or read\n", entry.name)
+		case sums[0] != entry.sum:
+			res.failed++
+			fmt.Fprintf(out, "%s: FAILED\n", entry.name)
+		default:
+			res.ok++
+			fmt.Fprintf(out, "%s: OK\n", entry.name)
+		}
+	}
+	return res, scanner.Err()
+}
+
+// verifyManifests verifies every named manifest, or standard input for "-",
+// warning about failures on standard error. It returns the exit status.
+func verifyManifests(ctx context.Context, names []string, def checksum.Algorithm, workers int) int {
+	status := 0
+	for _, name := range names {
+		res, err := verifyManifestFile(ctx, name, def, workers)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			status = 1
+			continue
+		}
+
+		warn(name, res.malformed, "line is improperly formatted", "lines are improperly formatted")
+		warn(name, res.missing, "listed file is missing", "listed files are missing")
+		warn(name, res.failed, "computed checksum did NOT match", "computed checksums did NOT match")
+		if res.ok+res.failed+res.missing == 0 {
+			fmt.Fprintf(os.Stderr, "%s: no properly formatted checksum lines found\n", name)
+		}
+		if !res.success() {
+			status = 1
+		}
+	}
+	return status
+}
+
+// verifyManifestFile verifies the named manifest, or standard input for "-".
+func verifyManifestFile(ctx context.Context, name string, def checksum.Algorithm, workers int) (verifyResult, error) {
+	r, err := openInput(name)
+	if err != nil {
+		return verifyResult{}, err
+	}
+	defer r.Close()
+
+	return verifyManifest(ctx, r, def, workers, os.Stdout)
+}
+
+func warn(name string, n int, singular, plural string) {
+	switch {
+	case n == 1:
+		fmt.Fprintf(os.Stderr, "%s: WARNING: 1 %s\n", name, singular)
+	case n > 1:
+		fmt.Fprintf(os.Stderr, "%s: WARNING: %d %s\n", name, n, plural)
+	}
+}
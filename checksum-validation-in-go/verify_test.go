@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParseManifestLine is the unit test to test parseManifestLine function.
+func TestParseManifestLine(t *testing.T) {
+	sha256Sum := "9e52d720c78aeb0411da37c1d25c8950485586ca392da33f2961da8caad91169"
+	md5Sum := "408818265c03bda82e531d6f575183d7"
+
+	var tests = []struct {
+		line    string
+		alg     string
+		name    string
+		sum     string
+		wantErr bool
+	}{
+		{line: sha256Sum + "  secret.txt", alg: "sha256", name: "secret.txt", sum: sha256Sum},
+		{line: sha256Sum + " *secret.txt", alg: "sha256", name: "secret.txt", sum: sha256Sum},
+		{line: md5Sum + "  two  spaces.txt", alg: "md5", name: "two  spaces.txt", sum: md5Sum},
+		{line: `\` + md5Sum + `  back\\slash\nnewline`, alg: "md5", name: "back\\slash\nnewline", sum: md5Sum},
+		{line: "SHA256 (secret.txt) = " + sha256Sum, alg: "sha256", name: "secret.txt", sum: sha256Sum},
+		{line: "MD5 (a (1).txt) = " + md5Sum, alg: "md5", name: "a (1).txt", sum: md5Sum},
+		{line: sha256Sum + "secret.txt", wantErr: true},
+		{line: "abc  secret.txt", wantErr: true},
+		{line: "SHA256 (secret.txt) " + sha256Sum, wantErr: true},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when parsing `%s`", td.line)
+		t.Run(testname, func(t *testing.T) {
+			entry, err := parseManifestLine(td.line, "")
+			if td.wantErr {
+				if err == nil {
+					t.Errorf("got %+v, want an error", entry)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if string(entry.alg) != td.alg || entry.name != td.name || entry.sum != td.sum {
+				t.Errorf("got %s %q %s, want %s %q %s", entry.alg, entry.name, entry.sum, td.alg, td.name, td.sum)
+			}
+		})
+	}
+}
+
+// TestVerifyManifest is the unit test to test verifyManifest function.
+func TestVerifyManifest(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "hello\n", "b.txt": "world\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, b, c := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt"), filepath.Join(dir, "c.txt")
+
+	// the checksums of "hello\n".
+	sha256Sum := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	md5Sum := "b1946ac92492d2347c6235b4d2611184"
+
+	var tests = []struct {
+		format   string
+		manifest []string
+		want     []string
+		res      verifyResult
+		success  bool
+	}{
+		{
+			format:   "GNU",
+			manifest: []string{"# comment", sha256Sum + "  " + a, md5Sum + " *" + a},
+			want:     []string{a + ": OK", a + ": OK"},
+			res:      verifyResult{ok: 2},
+			success:  true,
+		},
+		{
+			format:   "GNU",
+			manifest: []string{sha256Sum + "  " + a, sha256Sum + "  " + b, md5Sum + "  " + c, "not a checksum line"},
+			want:     []string{a + ": OK", b + ": FAILED", c + ": MISSING"},
+			res:      verifyResult{ok: 1, failed: 1, missing: 1, malformed: 1},
+		},
+		{
+			format:   "BSD",
+			manifest: []string{"SHA256 (" + a + ") = " + sha256Sum, "MD5 (" + a + ") = " + strings.ToUpper(md5Sum)},
+			want:     []string{a + ": OK", a + ": OK"},
+			res:      verifyResult{ok: 2},
+			success:  true,
+		},
+		{
+			format:   "BSD",
+			manifest: []string{"MD5 (" + b + ") = " + md5Sum, "SHA256 (" + c + ") = " + sha256Sum, "SHA256 (" + a + ") " + sha256Sum},
+			want:     []string{b + ": FAILED", c + ": MISSING"},
+			res:      verifyResult{failed: 1, missing: 1, malformed: 1},
+		},
+		{
+			format:   "GNU",
+			manifest: []string{"not a checksum line"},
+			res:      verifyResult{malformed: 1},
+		},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when verifying a %s manifest of %d lines", td.format, len(td.manifest))
+		t.Run(testname, func(t *testing.T) {
+			var out bytes.Buffer
+			res, err := verifyManifest(context.Background(), strings.NewReader(strings.Join(td.manifest, "\n")), "", 1, &out)
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+
+			var want string
+			for _, line := range td.want {
+				want += line + "\n"
+			}
+			if got := out.String(); got != want {
+				t.Errorf("got output %q, want %q", got, want)
+			}
+			if res != td.res {
+				t.Errorf("got %+v, want %+v", res, td.res)
+			}
+			if got := res.success(); got != td.success {
+				t.Errorf("got success %t, want %t", got, td.success)
+			}
+		})
+	}
+}
+
+// TestVerifyManifests is the unit test to test verifyManifests function.
+func TestVerifyManifests(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(a, []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sha256Sum := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+
+	var tests = []struct {
+		manifest string
+		want     int
+	}{
+		{manifest: sha256Sum + "  " + a + "\n", want: 0},
+		{manifest: strings.Repeat("0", 64) + "  " + a + "\n", want: 1},
+		{manifest: sha256Sum + "  " + filepath.Join(dir, "missing.txt") + "\n", want: 1},
+		{manifest: "# no checksum lines\n", want: 1},
+	}
+
+	for i, td := range tests {
+		testname := fmt.Sprintf("when verifying manifest %d", i)
+		t.Run(testname, func(t *testing.T) {
+			name := filepath.Join(dir, fmt.Sprintf("manifest%d", i))
+			if err := os.WriteFile(name, []byte(td.manifest), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if got := verifyManifests(context.Background(), []string{name}, "", 1); got != td.want {
+				t.Errorf("got exit status %d, want %d", got, td.want)
+			}
+		})
+	}
+	if got := verifyManifests(context.Background(), []string{filepath.Join(dir, "no-manifest")}, "", 1); got != 1 {
+		t.Errorf("got exit status %d for a missing manifest, want 1", got)
+	}
+}
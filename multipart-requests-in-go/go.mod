@@ -0,0 +1,7 @@
+module github.com/abvarun226/multipart-requests-in-go
+
+go 1.16
+
+require github.com/abvarun226/checksum-validation-in-go v0.0.0
+
+replace github.com/abvarun226/checksum-validation-in-go => ../checksum-validation-in-go
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -8,6 +9,8 @@ import (
 	"mime/multipart"
 	"net/http"
 	"os"
+
+	"github.com/abvarun226/checksum-validation-in-go/checksum"
 )
 
 func main() {
@@ -38,23 +41,62 @@ func main() {
 		log.Print(string(metadata))
 
 		for _, h := range r.MultipartForm.File["media"] {
+			// the client may announce the checksum of each media part with a
+			// Content-MD5 or Digest header, checked before the file is kept.
+			alg, sum, err := checksum.DigestFromHeader(h.Header)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
 			file, err := h.Open()
 			if err != nil {
 				http.Error(w, "failed to get media form file", http.StatusBadRequest)
 				return
 			}
-			uploadMedia(file, h.Filename)
+
+			if err := uploadMedia(file, h.Filename, alg, sum); err != nil {
+				if errors.Is(err, checksum.ErrMismatch) {
+					http.Error(w, fmt.Sprintf("%s: %v", h.Filename, err), http.StatusBadRequest)
+					return
+				}
+				http.Error(w, "failed to save media", http.StatusInternalServerError)
+				return
+			}
 		}
 	})
 
 	http.ListenAndServe(":8080", mux)
 }
 
-func uploadMedia(file multipart.File, filename string) {
+// uploadMedia saves file under filename. When alg is set, the file is only
+// kept if its checksum is sum.
+func uploadMedia(file multipart.File, filename string, alg checksum.Algorithm, sum []byte) error {
 	defer file.Close()
-	tmpfile, _ := os.Create("./" + filename)
-	defer tmpfile.Close()
-	io.Copy(tmpfile, file)
+
+	var media io.Reader = file
+	if alg != "" {
+		media = checksum.NewVerifyingReader(file, alg, sum)
+	}
+
+	// write to a temporary file first, so that nothing is left behind when
+	// the checksum turns out to be wrong.
+	tmpname := "./" + filename + ".part"
+	tmpfile, err := os.Create(tmpname)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmpfile, media); err != nil {
+		tmpfile.Close()
+		os.Remove(tmpname)
+		return err
+	}
+	if err := tmpfile.Close(); err != nil {
+		os.Remove(tmpname)
+		return err
+	}
+
+	return os.Rename(tmpname, "./"+filename)
 }
 
 func getMetadata(r *http.Request) ([]byte, error) {
@@ -2,6 +2,8 @@ package main
 
 import (
 	"bytes"
+	"crypto/md5"
+	"encoding/base64"
 	"flag"
 	"fmt"
 	"io"
@@ -48,6 +50,7 @@ func main() {
 		mediaHeader.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%v\".", mediaFilename))
 		mediaHeader.Set("Content-ID", "media")
 		mediaHeader.Set("Content-Filename", mediaFilename)
+		mediaHeader.Set("Content-MD5", contentMD5(mediaData))
 
 		mediaPart, err := writer.CreatePart(mediaHeader)
 		if err != nil {
@@ -89,4 +92,11 @@ func main() {
 	} else {
 		log.Print("Request was a success")
 	}
-}
\ No newline at end of file
+}
+
+// contentMD5 returns the value of the Content-MD5 header for data, which the
+// server uses to verify the media it receives.
+func contentMD5(data []byte) string {
+	sum := md5.Sum(data)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
-	"os"
 	"mime"
 	"mime/multipart"
 	"net/http"
+	"os"
 	"strings"
+
+	"github.com/abvarun226/checksum-validation-in-go/checksum"
 )
 
 func main() {
@@ -34,25 +38,68 @@ func main() {
 				return
 			}
 			defer part.Close()
-		
-			fileBytes, err := ioutil.ReadAll(part)
-			if err != nil {
-				http.Error(w, "failed to read content of the part", http.StatusInternalServerError)
-				return
-			}
 
 			switch part.Header.Get("Content-ID") {
 			case "metadata":
+				fileBytes, err := ioutil.ReadAll(part)
+				if err != nil {
+					http.Error(w, "failed to read content of the part", http.StatusInternalServerError)
+					return
+				}
 				log.Print(string(fileBytes))
-		
+
 			case "media":
-				log.Printf("filesize = %d", len(fileBytes))
-				f, _ := os.Create(part.Header.Get("Content-Filename"))
-				f.Write(fileBytes)
-				f.Close()
+				// the client may announce the checksum of each media part with a
+				// Content-MD5 or Digest header, checked before the file is kept.
+				alg, sum, err := checksum.DigestFromHeader(part.Header)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				filename := part.Header.Get("Content-Filename")
+				size, err := saveMedia(part, filename, alg, sum)
+				if err != nil {
+					if errors.Is(err, checksum.ErrMismatch) {
+						http.Error(w, fmt.Sprintf("%s: %v", filename, err), http.StatusBadRequest)
+						return
+					}
+					http.Error(w, "failed to read content of the part", http.StatusInternalServerError)
+					return
+				}
+				log.Printf("filesize = %d", size)
 			}
 		}
 	})
 
 	http.ListenAndServe(":8080", mux)
-}
\ No newline at end of file
+}
+
+// saveMedia saves the content of a media part under filename, returning its size.
+// When alg is set, the file is only kept if its checksum is sum.
+func saveMedia(part io.Reader, filename string, alg checksum.Algorithm, sum []byte) (int64, error) {
+	media := part
+	if alg != "" {
+		media = checksum.NewVerifyingReader(part, alg, sum)
+	}
+
+	// write to a temporary file first, so that nothing is left behind when
+	// the checksum turns out to be wrong.
+	tmpname := filename + ".part"
+	f, err := os.Create(tmpname)
+	if err != nil {
+		return 0, err
+	}
+	size, err := io.Copy(f, media)
+	if err != nil {
+		f.Close()
+		os.Remove(tmpname)
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpname)
+		return 0, err
+	}
+
+	return size, os.Rename(tmpname, filename)
+}
@@ -0,0 +1,604 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"coding-library/sqsctl/queue"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// errQueueRequired is returned by commands run without -q.
+var errQueueRequired = errors.New("-q argument is required. Specify a name for the queue")
+
+// newFlagSet returns the flag set of a command acting on the queue named by -q.
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet("sqsctl "+name, flag.ExitOnError)
+	q := fs.String("q", "", "name of the queue")
+	return fs, q
+}
+
+func createCmd(ctx context.Context, api queue.SQSQueueAPI, args []string) error {
+	fs, q := newFlagSet("create")
+	attr := make(attributeFlag)
+	fs.Var(attr, "attr", "queue attribute as Name=Value, e.g. DelaySeconds=60, may be repeated")
+	fifo := fs.Bool("fifo", false, "create a FIFO queue, whose name must end with .fifo, the default for such names")
+	contentDedup := fs.Bool("content-dedup", false, "deduplicate the messages of a FIFO queue by the SHA-256 of their body")
+	dlq := fs.String("dlq", "", "name of the dead-letter queue, created when missing")
+	maxReceive := fs.Int("max-receive", 5, "receives after which messages are moved to the dead-letter queue")
+	fs.Parse(args)
+	if *q == "" {
+		return errQueueRequired
+	}
+	if *fifo {
+		attr["FifoQueue"] = "true"
+	}
+	if *contentDedup {
+		attr["ContentBasedDeduplication"] = "true"
+	}
+
+	if *dlq != "" {
+		queueURL, dlqURL, err := queue.CreateWithDLQ(ctx, api, *q, attr, *dlq, *maxReceive)
+		if err != nil {
+			return fmt.Errorf("error creating the queue: %w", err)
+		}
+		fmt.Println(queueURL)
+		fmt.Printf("dead-letter queue: %s\n", dlqURL)
+		return nil
+	}
+
+	queueURL, err := queue.Create(ctx, api, *q, attr)
+	if err != nil {
+		return fmt.Errorf("error creating the queue: %w", err)
+	}
+	fmt.Println(queueURL)
+	return nil
+}
+
+func deleteCmd(ctx context.Context, api queue.SQSQueueAPI, args []string) error {
+	fs, q := newFlagSet("delete")
+	fs.Parse(args)
+	if *q == "" {
+		return errQueueRequired
+	}
+
+	queueURL, err := queue.Delete(ctx, api, *q)
+	if err != nil {
+		return fmt.Errorf("error deleting the queue: %w", err)
+	}
+	fmt.Printf("deleted queue with URL: %s\n", queueURL)
+	return nil
+}
+
+func listCmd(ctx context.Context, api queue.SQSQueueAPI, args []string) error {
+	fs := flag.NewFlagSet("sqsctl list", flag.ExitOnError)
+	prefix := fs.String("prefix", "", "only list queues whose name starts with prefix")
+	tags := make(attributeFlag)
+	fs.Var(tags, "tag", "only list queues with the tag Name=Value, may be repeated")
+	withTags := fs.Bool("tags", false, "print the tags of the queues")
+	attrs := fs.String("attrs", "", "comma-separated queue attributes to print, e.g. ApproximateNumberOfMessages,VisibilityTimeout, or All")
+	output := fs.String("o", "text", "output format: text, json or csv")
+	fs.Parse(args)
+
+	opts := queue.ListOptions{Prefix: *prefix, Tags: tags, WithTags: *withTags}
+	if *attrs != "" {
+		opts.Attributes = strings.Split(*attrs, ",")
+	}
+	queues, err := queue.ListDetailed(ctx, api, opts)
+	if err != nil {
+		return fmt.Errorf("error listing the queues: %w", err)
+	}
+
+	switch *output {
+	case "text":
+		// only the URLs, unless tags or attributes are asked for.
+		if !*withTags && *attrs == "" {
+			for _, q := range queues {
+				fmt.Println(q.URL)
+			}
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, row := range listingRows(queues, *withTags) {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(queues)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.WriteAll(listingRows(queues, *withTags))
+		return w.Error()
+	}
+	return fmt.Errorf("unknown output format %q, want text, json or csv", *output)
+}
+
+// listingRows returns a header and a row for every queue, with a column for
+// every attribute returned and one for the tags when withTags is set.
+func listingRows(queues []queue.QueueListing, withTags bool) [][]string {
+	seen := make(map[string]bool)
+	var attrs []string
+	for _, q := range queues {
+		for name := range q.Attributes {
+			if !seen[name] {
+				seen[name] = true
+				attrs = append(attrs, name)
+			}
+		}
+	}
+	sort.Strings(attrs)
+
+	header := append([]string{"Name", "URL"}, attrs...)
+	if withTags {
+		header = append(header, "Tags")
+	}
+	rows := [][]string{header}
+	for _, q := range queues {
+		row := []string{q.Name, q.URL}
+		for _, name := range attrs {
+			row = append(row, q.Attributes[name])
+		}
+		if withTags {
+			tags := make([]string, 0, len(q.Tags))
+			for k, v := range q.Tags {
+				tags = append(tags, k+"="+v)
+			}
+			sort.Strings(tags)
+			row = append(row, strings.Join(tags, ","))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func sendCmd(ctx context.Context, api queue.SQSQueueAPI, args []string) error {
+	fs, q := newFlagSet("send")
+	delay := fs.Int("delay", 0, "seconds to delay the delivery of the message")
+	attr := make(messageAttributeFlag)
+	fs.Var(attr, "attr", "message attribute as Name=Value, or Name:Number=Value for numbers, may be repeated")
+	group := fs.String("group", "", "message group id, required by FIFO queues")
+	dedup := fs.String("dedup", "", "message deduplication id, for FIFO queues without content-based deduplication")
+	file := fs.String("f", "", `send the messages of a file, or standard input for "-", holding one JSON object per line such as {"body": "...", "delay": 10, "group": "...", "dedup": "...", "attributes": {"Blog": "The Code Library", "Article": 10}}`)
+	fs.Parse(args)
+	if *q == "" {
+		return errQueueRequired
+	}
+
+	if *file != "" {
+		defaults := queue.Message{Delay: int32(*delay), Attributes: attr, GroupID: *group}
+		return sendBatch(ctx, api, *q, *file, defaults)
+	}
+
+	// the body is the remaining arguments, or standard input when there are none.
+	body := strings.Join(fs.Args(), " ")
+	if fs.NArg() == 0 {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("error reading the message: %w", err)
+		}
+		body = string(b)
+	}
+
+	msg := queue.Message{
+		Body:            body,
+		Delay:           int32(*delay),
+		Attributes:      attr,
+		GroupID:         *group,
+		DeduplicationID: *dedup,
+	}
+	if offload != nil {
+		var err error
+		if msg, err = offload.Store(ctx, msg); err != nil {
+			return err
+		}
+	}
+
+	id, err := queue.Send(ctx, api, *q, msg)
+	if err != nil {
+		return fmt.Errorf("error sending the message: %w", err)
+	}
+	fmt.Printf("Message ID: %s\n", id)
+	return nil
+}
+
+// sendBatch sends the messages of the named NDJSON file, or standard input for "-",
+// with the delay, attributes and group of defaults for the messages that don't set them.
+func sendBatch(ctx context.Context, api queue.SQSQueueAPI, q, name string, defaults queue.Message) error {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	msgs, lines, err := readMessages(r)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	for i := range msgs {
+		if msgs[i].Delay == 0 {
+			msgs[i].Delay = defaults.Delay
+		}
+		if msgs[i].GroupID == "" {
+			msgs[i].GroupID = defaults.GroupID
+		}
+		for k, v := range defaults.Attributes {
+			if _, ok := msgs[i].Attributes[k]; !ok {
+				if msgs[i].Attributes == nil {
+					msgs[i].Attributes = make(map[string]types.MessageAttributeValue)
+				}
+				msgs[i].Attributes[k] = v
+			}
+		}
+		if offload != nil {
+			if msgs[i], err = offload.Store(ctx, msgs[i]); err != nil {
+				return fmt.Errorf("%s:%d: %w", name, lines[i], err)
+			}
+		}
+	}
+
+	ids, err := queue.SendBatch(ctx, api, q, msgs)
+	for _, id := range ids {
+		if id != "" {
+			fmt.Printf("Message ID: %s\n", id)
+		}
+	}
+
+	var batchErr *queue.BatchError
+	if errors.As(err, &batchErr) {
+		failed := make([]int, 0, len(batchErr.Failed))
+		for i := range batchErr.Failed {
+			failed = append(failed, i)
+		}
+		sort.Ints(failed)
+		for _, i := range failed {
+			fmt.Fprintf(os.Stderr, "%s:%d: error sending the message: %v\n", name, lines[i], batchErr.Failed[i])
+		}
+		return fmt.Errorf("%d of %d messages were not sent", len(batchErr.Failed), len(msgs))
+	}
+	if err != nil {
+		return fmt.Errorf("error sending the messages: %w", err)
+	}
+	return nil
+}
+
+// batchLine is a line of an NDJSON file of messages.
+type batchLine struct {
+	Body       string                 `json:"body"`
+	Delay      int32                  `json:"delay"`
+	Group      string                 `json:"group"`
+	Dedup      string                 `json:"dedup"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// readMessages reads messages from NDJSON lines, skipping blank ones, and returns
+// them along with the line number of each.
+func readMessages(r io.Reader) ([]queue.Message, []int, error) {
+	var (
+		msgs  []queue.Message
+		lines []int
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		var line batchLine
+		dec := json.NewDecoder(strings.NewReader(scanner.Text()))
+		dec.UseNumber()
+		if err := dec.Decode(&line); err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		msg := queue.Message{Body: line.Body, Delay: line.Delay, GroupID: line.Group, DeduplicationID: line.Dedup}
+		for name, v := range line.Attributes {
+			attr, err := messageAttribute(v)
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: attribute %s: %w", n, name, err)
+			}
+			if msg.Attributes == nil {
+				msg.Attributes = make(map[string]types.MessageAttributeValue)
+			}
+			msg.Attributes[name] = attr
+		}
+		msgs = append(msgs, msg)
+		lines = append(lines, n)
+	}
+	return msgs, lines, scanner.Err()
+}
+
+// messageAttribute converts a JSON string or number to a message attribute.
+func messageAttribute(v interface{}) (types.MessageAttributeValue, error) {
+	switch v := v.(type) {
+	case string:
+		return types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(v)}, nil
+	case json.Number:
+		return types.MessageAttributeValue{DataType: aws.String("Number"), StringValue: aws.String(v.String())}, nil
+	default:
+		return types.MessageAttributeValue{}, fmt.Errorf("want a string or a number, got %v", v)
+	}
+}
+
+func receiveCmd(ctx context.Context, api queue.SQSQueueAPI, args []string) error {
+	fs, q := newFlagSet("receive")
+	max := fs.Int("n", 1, "maximum number of messages to receive, up to 10")
+	visibility := fs.Int("visibility", 10, "seconds the messages stay hidden from other consumers")
+	wait := fs.Int("wait", 0, "seconds to wait for messages to arrive, up to 20")
+	del := fs.Bool("delete", false, "delete the messages once printed")
+	fs.Parse(args)
+	if *q == "" {
+		return errQueueRequired
+	}
+
+	msgs, err := queue.Receive(ctx, api, *q, queue.ReceiveOptions{
+		MaxMessages:       int32(*max),
+		VisibilityTimeout: int32(*visibility),
+		WaitTime:          int32(*wait),
+	})
+	if err != nil {
+		return fmt.Errorf("error receiving messages: %w", err)
+	}
+
+	if len(msgs) == 0 {
+		fmt.Fprintln(os.Stderr, "No messages found")
+		return nil
+	}
+	handles := make([]string, 0, len(msgs))
+	for _, msg := range msgs {
+		body := msg
+		if offload != nil {
+			if body, err = offload.Load(ctx, msg); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("Message ID: %s, Message Body: %s\n", aws.ToString(body.MessageId), aws.ToString(body.Body))
+		handles = append(handles, aws.ToString(msg.ReceiptHandle))
+	}
+
+	if *del {
+		if err := queue.DeleteBatch(ctx, api, *q, handles); err != nil {
+			return fmt.Errorf("error deleting the messages: %w", err)
+		}
+		if offload != nil {
+			for _, msg := range msgs {
+				if err := offload.Release(ctx, msg); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func consumeCmd(ctx context.Context, api queue.SQSQueueAPI, args []string) error {
+	fs, q := newFlagSet("consume")
+	concurrency := fs.Int("c", 10, "number of messages handled at once")
+	visibility := fs.Int("visibility", 0, "seconds the messages stay hidden from other consumers, the queue's setting when 0")
+	heartbeat := fs.Duration("heartbeat", 0, "how often to extend the visibility timeout of messages being handled, never when 0")
+	maxVisibility := fs.Duration("max-visibility", 0, "longest time the heartbeat keeps a message hidden, 12h when 0")
+	fs.Parse(args)
+	if *q == "" {
+		return errQueueRequired
+	}
+
+	var mu sync.Mutex
+	handler := func(ctx context.Context, msg types.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Printf("Message ID: %s, Message Body: %s\n", aws.ToString(msg.MessageId), aws.ToString(msg.Body))
+		return nil
+	}
+
+	return queue.Consume(ctx, api, *q, handler, queue.ConsumerOptions{
+		Concurrency:       *concurrency,
+		VisibilityTimeout: int32(*visibility),
+		HeartbeatInterval: *heartbeat,
+		MaxVisibility:     *maxVisibility,
+		Offload:           offload,
+	})
+}
+
+func redriveCmd(ctx context.Context, api queue.SQSQueueAPI, args []string) error {
+	fs, q := newFlagSet("redrive")
+	to := fs.String("to", "", "name of the source queue, the only queue using the dead-letter queue when empty")
+	rate := fs.Float64("rate", 10, "messages moved per second, unlimited when 0")
+	max := fs.Int("n", 0, "number of messages to move, all of them when 0")
+	dryRun := fs.Bool("dry-run", false, "only print the messages that would be moved")
+	fs.Parse(args)
+	if *q == "" {
+		return errQueueRequired
+	}
+
+	moved, err := queue.Redrive(ctx, api, *q, *to, queue.RedriveOptions{
+		Rate:   *rate,
+		Max:    *max,
+		DryRun: *dryRun,
+		Visit: func(msg types.Message) {
+			fmt.Printf("Message ID: %s, Message Body: %s\n", aws.ToString(msg.MessageId), aws.ToString(msg.Body))
+		},
+	})
+	if *dryRun {
+		fmt.Printf("%d messages would be moved\n", moved)
+	} else {
+		fmt.Printf("%d messages moved\n", moved)
+	}
+	if err != nil {
+		return fmt.Errorf("error redriving messages: %w", err)
+	}
+	return nil
+}
+
+func purgeCmd(ctx context.Context, api queue.SQSQueueAPI, args []string) error {
+	fs, q := newFlagSet("purge")
+	fs.Parse(args)
+	if *q == "" {
+		return errQueueRequired
+	}
+
+	info, err := queue.Info(ctx, api, *q)
+	if err != nil {
+		return fmt.Errorf("error getting the queue attributes: %w", err)
+	}
+	if err := queue.Purge(ctx, api, *q); err != nil {
+		return fmt.Errorf("error purging the queue: %w", err)
+	}
+	// SQS takes up to a minute to delete the messages.
+	fmt.Printf("Purging about %d messages from %s\n", info.Messages+info.MessagesInFlight+info.MessagesDelayed, *q)
+	return nil
+}
+
+func attrsCmd(ctx context.Context, api queue.SQSQueueAPI, args []string) error {
+	fs, q := newFlagSet("attrs")
+	output := fs.String("o", "table", "output format: table, json, or raw for every attribute as Name=Value")
+	fs.Parse(args)
+	if *q == "" {
+		return errQueueRequired
+	}
+
+	info, err := queue.Info(ctx, api, *q)
+	if err != nil {
+		return fmt.Errorf("error getting the queue attributes: %w", err)
+	}
+
+	switch *output {
+	case "table":
+		return printInfo(os.Stdout, info)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	case "raw":
+		names := make([]string, 0, len(info.Attributes))
+		for name := range info.Attributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%s=%s\n", name, info.Attributes[name])
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, want table, json or raw", *output)
+}
+
+// printInfo writes the state and settings of a queue as a table.
+func printInfo(w io.Writer, info queue.QueueInfo) error {
+	seconds := func(n int) string {
+		return (time.Duration(n) * time.Second).String()
+	}
+	rows := [][2]string{
+		{"Name", info.Name},
+		{"URL", info.URL},
+		{"ARN", info.ARN},
+		{"FIFO", strconv.FormatBool(info.FIFO)},
+		{"Messages available", strconv.Itoa(info.Messages)},
+		{"Messages in flight", strconv.Itoa(info.MessagesInFlight)},
+		{"Messages delayed", strconv.Itoa(info.MessagesDelayed)},
+		{"Visibility timeout", seconds(info.VisibilityTimeout)},
+		{"Retention period", seconds(info.RetentionPeriod)},
+		{"Delivery delay", seconds(info.Delay)},
+		{"Receive wait time", seconds(info.ReceiveWaitTime)},
+		{"Maximum message size", fmt.Sprintf("%d bytes", info.MaxMessageSize)},
+	}
+	if info.DeadLetterQueue != "" {
+		rows = append(rows,
+			[2]string{"Dead-letter queue", info.DeadLetterQueue},
+			[2]string{"Maximum receives", strconv.Itoa(info.MaxReceiveCount)})
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\n", row[0], row[1])
+	}
+	return tw.Flush()
+}
+
+func updateCmd(ctx context.Context, api queue.SQSQueueAPI, args []string) error {
+	fs, q := newFlagSet("update")
+	attr := make(attributeFlag)
+	fs.Var(attr, "attr", "queue attribute as Name=Value, e.g. VisibilityTimeout=60, may be repeated")
+	fs.Parse(args)
+	if *q == "" {
+		return errQueueRequired
+	}
+	if len(attr) == 0 {
+		return errors.New("-attr argument is required. Specify the attributes to set")
+	}
+
+	if err := queue.SetAttributes(ctx, api, *q, attr); err != nil {
+		return fmt.Errorf("error updating the queue attributes: %w", err)
+	}
+	return nil
+}
+
+// attributeFlag collects repeated Name=Value flags.
+type attributeFlag map[string]string
+
+func (a attributeFlag) String() string {
+	return fmt.Sprint(map[string]string(a))
+}
+
+func (a attributeFlag) Set(s string) error {
+	i := strings.IndexByte(s, '=')
+	if i <= 0 {
+		return fmt.Errorf("%q is not Name=Value", s)
+	}
+	a[s[:i]] = s[i+1:]
+	return nil
+}
+
+// messageAttributeFlag collects repeated Name=Value or Name:Type=Value flags,
+// where Type is String, the default, or Number.
+type messageAttributeFlag map[string]types.MessageAttributeValue
+
+func (a messageAttributeFlag) String() string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func (a messageAttributeFlag) Set(s string) error {
+	i := strings.IndexByte(s, '=')
+	if i <= 0 {
+		return fmt.Errorf("%q is not Name=Value", s)
+	}
+	name, value := s[:i], s[i+1:]
+
+	dataType := "String"
+	if j := strings.LastIndexByte(name, ':'); j >= 0 {
+		name, dataType = name[:j], name[j+1:]
+	}
+	if dataType != "String" && dataType != "Number" {
+		return fmt.Errorf("unsupported attribute type %q, want String or Number", dataType)
+	}
+
+	a[name] = types.MessageAttributeValue{
+		DataType:    aws.String(dataType),
+		StringValue: aws.String(value),
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"coding-library/sqsctl/queue"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// TestMessageAttributeFlag is the unit test to test messageAttributeFlag type.
+func TestMessageAttributeFlag(t *testing.T) {
+	var tests = []struct {
+		value    string
+		name     string
+		dataType string
+		want     string
+		wantErr  bool
+	}{
+		{value: "Blog=The Code Library", name: "Blog", dataType: "String", want: "The Code Library"},
+		{value: "Article:Number=10", name: "Article", dataType: "Number", want: "10"},
+		{value: "Query=a=b", name: "Query", dataType: "String", want: "a=b"},
+		{value: "Blog", wantErr: true},
+		{value: "=value", wantErr: true},
+		{value: "Logo:Binary=AAAA", wantErr: true},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when testing %q", td.value)
+		t.Run(testname, func(t *testing.T) {
+			attr := make(messageAttributeFlag)
+			err := attr.Set(td.value)
+			if td.wantErr {
+				if err == nil {
+					t.Errorf("got %v, want an error", attr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+
+			got := attr[td.name]
+			if aws.ToString(got.DataType) != td.dataType || aws.ToString(got.StringValue) != td.want {
+				t.Errorf("got %s %q, want %s %q", aws.ToString(got.DataType), aws.ToString(got.StringValue), td.dataType, td.want)
+			}
+		})
+	}
+}
+
+// TestReadMessages is the unit test to test readMessages function.
+func TestReadMessages(t *testing.T) {
+	input := `{"body": "order 1"}
+
+{"body": "order 2", "delay": 10, "attributes": {"Blog": "The Code Library", "Article": 10}}
+`
+	msgs, lines, err := readMessages(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if len(msgs) != 2 || fmt.Sprint(lines) != "[1 3]" {
+		t.Fatalf("got %d messages on lines %v, want 2 on lines [1 3]", len(msgs), lines)
+	}
+	if msgs[1].Body != "order 2" || msgs[1].Delay != 10 {
+		t.Errorf("got %+v, want order 2 delayed by 10s", msgs[1])
+	}
+	if got := msgs[1].Attributes["Article"]; aws.ToString(got.DataType) != "Number" || aws.ToString(got.StringValue) != "10" {
+		t.Errorf("got %s %s, want Number 10", aws.ToString(got.DataType), aws.ToString(got.StringValue))
+	}
+	if got := msgs[1].Attributes["Blog"]; aws.ToString(got.DataType) != "String" {
+		t.Errorf("got %s, want String", aws.ToString(got.DataType))
+	}
+
+	for _, bad := range []string{`{"body": `, `{"body": "x", "attributes": {"Tags": ["a"]}}`} {
+		if _, _, err := readMessages(strings.NewReader(bad)); err == nil {
+			t.Errorf("got no error for %s", bad)
+		}
+	}
+}
+
+// TestPrintInfo is the unit test to test printInfo function.
+func TestPrintInfo(t *testing.T) {
+	var tests = []struct {
+		info queue.QueueInfo
+		want []string
+		omit string
+	}{
+		{
+			info: queue.QueueInfo{Name: "orders", Messages: 4, VisibilityTimeout: 30, RetentionPeriod: 345600},
+			want: []string{"Name                  orders\n", "Messages available    4\n", "Visibility timeout    30s\n", "Retention period      96h0m0s\n"},
+			omit: "Dead-letter queue",
+		},
+		{
+			info: queue.QueueInfo{Name: "orders", DeadLetterQueue: "arn:aws:sqs:us-east-1:000000000000:orders-dlq", MaxReceiveCount: 5},
+			want: []string{"Dead-letter queue     arn:aws:sqs:us-east-1:000000000000:orders-dlq\n", "Maximum receives      5\n"},
+		},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when printing %+v", td.info)
+		t.Run(testname, func(t *testing.T) {
+			var b strings.Builder
+			if err := printInfo(&b, td.info); err != nil {
+				t.Fatal(err)
+			}
+			for _, line := range td.want {
+				if !strings.Contains(b.String(), line) {
+					t.Errorf("got\n%s\nwant a line %q", b.String(), line)
+				}
+			}
+			if td.omit != "" && strings.Contains(b.String(), td.omit) {
+				t.Errorf("got\n%s\nwant no %q", b.String(), td.omit)
+			}
+		})
+	}
+}
+
+// TestListingRows is the unit test to test listingRows function.
+func TestListingRows(t *testing.T) {
+	queues := []queue.QueueListing{
+		{Name: "orders", URL: "http://sqs.test/orders", Tags: map[string]string{"team": "shop", "env": "prod"}, Attributes: map[string]string{"VisibilityTimeout": "30"}},
+		{Name: "invoices", URL: "http://sqs.test/invoices", Attributes: map[string]string{"DelaySeconds": "5"}},
+	}
+
+	var tests = []struct {
+		withTags bool
+		want     string
+	}{
+		{want: "[[Name URL DelaySeconds VisibilityTimeout] [orders http://sqs.test/orders  30] [invoices http://sqs.test/invoices 5 ]]"},
+		{withTags: true, want: "[[Name URL DelaySeconds VisibilityTimeout Tags] [orders http://sqs.test/orders  30 env=prod,team=shop] [invoices http://sqs.test/invoices 5  ]]"},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when listing with tags %t", td.withTags)
+		t.Run(testname, func(t *testing.T) {
+			if got := fmt.Sprint(listingRows(queues, td.withTags)); got != td.want {
+				t.Errorf("got %s, want %s", got, td.want)
+			}
+		})
+	}
+}
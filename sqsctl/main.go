@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"coding-library/sqsctl/queue"
+)
+
+// command is a subcommand of sqsctl.
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, api queue.SQSQueueAPI, args []string) error
+}
+
+var commands = []command{
+	{name: "create", usage: "create a queue", run: createCmd},
+	{name: "delete", usage: "delete a queue", run: deleteCmd},
+	{name: "list", usage: "list queues", run: listCmd},
+	{name: "send", usage: "send a message to a queue", run: sendCmd},
+	{name: "receive", usage: "receive messages from a queue", run: receiveCmd},
+	{name: "consume", usage: "print and delete messages from a queue until interrupted", run: consumeCmd},
+	{name: "redrive", usage: "move messages from a dead-letter queue back to its source queue", run: redriveCmd},
+	{name: "purge", usage: "delete every message in a queue", run: purgeCmd},
+	{name: "attrs", usage: "print the message counts and settings of a queue", run: attrsCmd},
+	{name: "update", usage: "change the attributes of a queue", run: updateCmd},
+}
+
+// offload stores large message bodies in S3 when -bucket is set.
+var offload *queue.Offload
+
+func main() {
+	var conf queue.Config
+	var o queue.Offload
+	flag.StringVar(&conf.Endpoint, "endpoint", envOr("SQSCTL_ENDPOINT", "AWS_ENDPOINT_URL"), "SQS endpoint, e.g. http://127.0.0.1:4566 for localstack (env SQSCTL_ENDPOINT)")
+	flag.StringVar(&conf.Region, "region", envOr("SQSCTL_REGION"), "AWS region, defaults to the aws configuration (env SQSCTL_REGION)")
+	flag.StringVar(&conf.Profile, "profile", envOr("SQSCTL_PROFILE"), "AWS shared configuration profile (env SQSCTL_PROFILE)")
+	flag.StringVar(&o.Bucket, "bucket", envOr("SQSCTL_BUCKET"), "S3 bucket storing the bodies of messages too large to send, read back when receiving (env SQSCTL_BUCKET)")
+	flag.StringVar(&o.Prefix, "bucket-prefix", "", "prefix of the keys of the message bodies stored in the bucket")
+	flag.IntVar(&o.Threshold, "offload-size", 256<<10, "size in bytes above which message bodies are stored in the bucket")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := lookup(flag.Arg(0))
+	if !ok {
+		fmt.Fprintf(os.Stderr, "sqsctl: unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	c, err := queue.NewClient(ctx, conf)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sqsctl: configuration error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if o.Bucket != "" {
+		if o.API, err = queue.NewS3Client(ctx, conf); err != nil {
+			fmt.Fprintf(os.Stderr, "sqsctl: configuration error: %v\n", err)
+			os.Exit(1)
+		}
+		offload = &o
+	}
+
+	if err := cmd.run(ctx, c, flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "sqsctl %s: %v\n", cmd.name, err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+func lookup(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "usage: sqsctl [flags] <command> [command flags]\n\ncommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(out, "\nflags:\n")
+	flag.PrintDefaults()
+}
+
+// envOr returns the value of the first environment variable that is set.
+func envOr(keys ...string) string {
+	for _, key := range keys {
+		if v := os.Getenv(key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
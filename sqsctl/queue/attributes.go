@@ -0,0 +1,193 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// QueueInfo is the state and main settings of a queue, read from its attributes.
+// Durations are in seconds.
+type QueueInfo struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	ARN  string `json:"arn"`
+	FIFO bool   `json:"fifo"`
+
+	// approximate numbers of messages available, being handled and delayed.
+	Messages         int `json:"messages"`
+	MessagesInFlight int `json:"messagesInFlight"`
+	MessagesDelayed  int `json:"messagesDelayed"`
+
+	VisibilityTimeout int `json:"visibilityTimeout"`
+	RetentionPeriod   int `json:"retentionPeriod"`
+	Delay             int `json:"delay"`
+	ReceiveWaitTime   int `json:"receiveWaitTime"`
+	MaxMessageSize    int `json:"maxMessageSize"` // bytes
+
+	// DeadLetterQueue is the ARN of the queue messages received MaxReceiveCount
+	// times are moved to, if any.
+	DeadLetterQueue string `json:"deadLetterQueue,omitempty"`
+	MaxReceiveCount int    `json:"maxReceiveCount,omitempty"`
+
+	Attributes map[string]string `json:"attributes"`
+}
+
+// Info returns the state and settings of the named queue.
+func Info(ctx context.Context, api SQSQueueAPI, name string) (QueueInfo, error) {
+	queueURL, err := URL(ctx, api, name)
+	if err != nil {
+		return QueueInfo{}, err
+	}
+	attr, err := queueAttributes(ctx, api, queueURL)
+	if err != nil {
+		return QueueInfo{}, err
+	}
+
+	info := QueueInfo{
+		Name:       name,
+		URL:        queueURL,
+		ARN:        attr["QueueArn"],
+		FIFO:       attr["FifoQueue"] == "true",
+		Attributes: attr,
+	}
+	for name, v := range map[string]*int{
+		"ApproximateNumberOfMessages":           &info.Messages,
+		"ApproximateNumberOfMessagesNotVisible": &info.MessagesInFlight,
+		"ApproximateNumberOfMessagesDelayed":    &info.MessagesDelayed,
+		"VisibilityTimeout":                     &info.VisibilityTimeout,
+		"MessageRetentionPeriod":                &info.RetentionPeriod,
+		"DelaySeconds":                          &info.Delay,
+		"ReceiveMessageWaitTimeSeconds":         &info.ReceiveWaitTime,
+		"MaximumMessageSize":                    &info.MaxMessageSize,
+	} {
+		if s, ok := attr[name]; ok {
+			if *v, err = strconv.Atoi(s); err != nil {
+				return QueueInfo{}, fmt.Errorf("attribute %s: %w", name, err)
+			}
+		}
+	}
+	if s := attr["RedrivePolicy"]; s != "" {
+		if info.DeadLetterQueue, info.MaxReceiveCount, err = parseRedrivePolicy(s); err != nil {
+			return QueueInfo{}, fmt.Errorf("attribute RedrivePolicy: %w", err)
+		}
+	}
+	return info, nil
+}
+
+// SetAttributes sets attributes of the named queue, after checking SQS accepts
+// their values. Attributes that can only be set at creation are rejected.
+func SetAttributes(ctx context.Context, api SQSQueueAPI, name string, attr map[string]string) error {
+	if _, ok := attr["FifoQueue"]; ok {
+		return fmt.Errorf("%w: FifoQueue can only be set when creating a queue", ErrInvalidOption)
+	}
+	if _, err := fifoAttributes(name, attr); err != nil {
+		return err
+	}
+	if err := validateAttributes(attr); err != nil {
+		return err
+	}
+	queueURL, err := URL(ctx, api, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = api.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+		QueueUrl:   aws.String(queueURL),
+		Attributes: attr,
+	})
+	return err
+}
+
+// attributeRanges holds the values allowed for the numeric queue attributes.
+var attributeRanges = map[string][2]int{
+	"DelaySeconds":                  {0, 900},
+	"MaximumMessageSize":            {1024, 262144},
+	"MessageRetentionPeriod":        {60, 1209600},
+	"ReceiveMessageWaitTimeSeconds": {0, 20},
+	"VisibilityTimeout":             {0, 43200},
+	"KmsDataKeyReusePeriodSeconds":  {60, 86400},
+}
+
+// attributeValues holds the values allowed for the enumerated queue attributes.
+var attributeValues = map[string][]string{
+	"FifoQueue":                 {"true", "false"},
+	"ContentBasedDeduplication": {"true", "false"},
+	"SqsManagedSseEnabled":      {"true", "false"},
+	"DeduplicationScope":        {"messageGroup", "queue"},
+	"FifoThroughputLimit":       {"perQueue", "perMessageGroupId"},
+}
+
+// validateAttributes checks attr only holds queue attributes that can be set,
+// with values SQS accepts.
+func validateAttributes(attr map[string]string) error {
+	for name, v := range attr {
+		if r, ok := attributeRanges[name]; ok {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < r[0] || n > r[1] {
+				return fmt.Errorf("%w: %s must be between %d and %d, got %q", ErrInvalidOption, name, r[0], r[1], v)
+			}
+			continue
+		}
+		if values, ok := attributeValues[name]; ok {
+			if !contains(values, v) {
+				return fmt.Errorf("%w: %s must be one of %s, got %q", ErrInvalidOption, name, strings.Join(values, ", "), v)
+			}
+			continue
+		}
+
+		switch name {
+		case "RedrivePolicy":
+			// an empty policy removes the dead-letter queue.
+			if v == "" {
+				continue
+			}
+			if _, _, err := parseRedrivePolicy(v); err != nil {
+				return fmt.Errorf("%w: RedrivePolicy: %v", ErrInvalidOption, err)
+			}
+		case "Policy", "RedriveAllowPolicy":
+			if v != "" && !json.Valid([]byte(v)) {
+				return fmt.Errorf("%w: %s must be a JSON document", ErrInvalidOption, name)
+			}
+		case "KmsMasterKeyId":
+		default:
+			return fmt.Errorf("%w: unknown queue attribute %s", ErrInvalidOption, name)
+		}
+	}
+	return nil
+}
+
+// parseRedrivePolicy returns the dead-letter queue ARN and maximum receive count
+// of a RedrivePolicy attribute, in which SQS returns the count as a number while
+// accepting it as a string.
+func parseRedrivePolicy(s string) (string, int, error) {
+	var policy struct {
+		DeadLetterTargetArn string          `json:"deadLetterTargetArn"`
+		MaxReceiveCount     json.RawMessage `json:"maxReceiveCount"`
+	}
+	if err := json.Unmarshal([]byte(s), &policy); err != nil {
+		return "", 0, err
+	}
+	if policy.DeadLetterTargetArn == "" {
+		return "", 0, fmt.Errorf("missing deadLetterTargetArn")
+	}
+	n, err := strconv.Atoi(strings.Trim(string(policy.MaxReceiveCount), `"`))
+	if err != nil || n < 1 || n > 1000 {
+		return "", 0, fmt.Errorf("maxReceiveCount must be between 1 and 1000, got %s", policy.MaxReceiveCount)
+	}
+	return policy.DeadLetterTargetArn, n, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// TestValidateAttributes is the unit test to test validateAttributes function.
+func TestValidateAttributes(t *testing.T) {
+	var tests = []struct {
+		attr map[string]string
+		ok   bool
+	}{
+		{attr: map[string]string{"VisibilityTimeout": "0", "DelaySeconds": "900", "MessageRetentionPeriod": "1209600"}, ok: true},
+		{attr: map[string]string{"MaximumMessageSize": "1024", "ReceiveMessageWaitTimeSeconds": "20"}, ok: true},
+		{attr: map[string]string{"RedrivePolicy": `{"deadLetterTargetArn": "arn:aws:sqs:us-east-1:000000000000:orders-dlq", "maxReceiveCount": 5}`}, ok: true},
+		{attr: map[string]string{"RedrivePolicy": `{"deadLetterTargetArn": "arn:aws:sqs:us-east-1:000000000000:orders-dlq", "maxReceiveCount": "5"}`}, ok: true},
+		{attr: map[string]string{"RedrivePolicy": ""}, ok: true},
+		{attr: map[string]string{"FifoQueue": "true", "DeduplicationScope": "messageGroup", "FifoThroughputLimit": "perMessageGroupId"}, ok: true},
+		{attr: map[string]string{"Policy": `{"Version": "2012-10-17"}`, "KmsMasterKeyId": "alias/aws/sqs"}, ok: true},
+		{attr: map[string]string{"VisibilityTimeout": "43201"}},
+		{attr: map[string]string{"VisibilityTimeout": "30s"}},
+		{attr: map[string]string{"MessageRetentionPeriod": "59"}},
+		{attr: map[string]string{"MaximumMessageSize": "262145"}},
+		{attr: map[string]string{"ReceiveMessageWaitTimeSeconds": "-1"}},
+		{attr: map[string]string{"RedrivePolicy": `{"maxReceiveCount": 5}`}},
+		{attr: map[string]string{"RedrivePolicy": `{"deadLetterTargetArn": "arn:aws:sqs:us-east-1:000000000000:orders-dlq", "maxReceiveCount": 1001}`}},
+		{attr: map[string]string{"ContentBasedDeduplication": "yes"}},
+		{attr: map[string]string{"Policy": "allow everyone"}},
+		{attr: map[string]string{"QueueArn": "arn:aws:sqs:us-east-1:000000000000:orders"}},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when validating %v", td.attr)
+		t.Run(testname, func(t *testing.T) {
+			err := validateAttributes(td.attr)
+			if td.ok && err != nil {
+				t.Errorf("got error %v", err)
+			}
+			if !td.ok && !errors.Is(err, ErrInvalidOption) {
+				t.Errorf("got %v, want %v", err, ErrInvalidOption)
+			}
+		})
+	}
+}
+
+// TestInfo is the unit test to test Info and SetAttributes functions.
+func TestInfo(t *testing.T) {
+	api := newFakeSQS()
+	if _, _, err := CreateWithDLQ(ctx, api, "orders", map[string]string{"VisibilityTimeout": "60"}, "orders-dlq", 3); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := Send(ctx, api, "orders", Message{Body: fmt.Sprint("order ", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Receive(ctx, api, "orders", ReceiveOptions{MaxMessages: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetAttributes(ctx, api, "orders", map[string]string{"MessageRetentionPeriod": "3600", "DelaySeconds": "5"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, attr := range []map[string]string{
+		{"VisibilityTimeout": "-1"},
+		{"FifoQueue": "false"},
+		{"ContentBasedDeduplication": "true"},
+	} {
+		if err := SetAttributes(ctx, api, "orders", attr); !errors.Is(err, ErrInvalidOption) {
+			t.Errorf("got %v when setting %v, want %v", err, attr, ErrInvalidOption)
+		}
+	}
+
+	info, err := Info(ctx, api, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := QueueInfo{
+		Name:              "orders",
+		URL:               info.URL,
+		ARN:               fakeARN("orders"),
+		Messages:          2,
+		MessagesInFlight:  1,
+		VisibilityTimeout: 60,
+		RetentionPeriod:   3600,
+		Delay:             5,
+		DeadLetterQueue:   fakeARN("orders-dlq"),
+		MaxReceiveCount:   3,
+		Attributes:        info.Attributes,
+	}
+	if info.URL == "" || info.Attributes["DelaySeconds"] != "5" || !reflect.DeepEqual(info, want) {
+		t.Errorf("got %+v, want %+v", info, want)
+	}
+}
@@ -0,0 +1,350 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+const (
+	// maxBatchEntries is the most entries a batch request takes.
+	maxBatchEntries = 10
+
+	// maxBatchBytes is the most a batch of messages, or a single message, may weigh.
+	maxBatchBytes = 256 << 10
+
+	// maxBatchAttempts is how many times an entry failing for a reason that is not
+	// its own is sent, with batchBackoff doubling between attempts.
+	maxBatchAttempts = 3
+	batchBackoff     = 200 * time.Millisecond
+)
+
+// ErrMessageTooLarge is returned for messages over the 256 KB SQS limit.
+var ErrMessageTooLarge = errors.New("message is larger than 256 KB")
+
+// ErrGroupFailed is returned for the messages of a FIFO queue that were not sent
+// because an earlier message of their group could not be sent.
+var ErrGroupFailed = errors.New("an earlier message of the group was not sent")
+
+// BatchError reports the entries of a batch operation that failed, by index.
+type BatchError struct {
+	Op     string
+	Failed map[int]error
+}
+
+func (e *BatchError) Error() string {
+	indexes := e.indexes()
+	msgs := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		msgs = append(msgs, fmt.Sprintf("%d: %v", i, e.Failed[i]))
+	}
+	return fmt.Sprintf("%s failed for %d of the messages: %s", e.Op, len(e.Failed), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the error of the first failed entry, so that errors.Is
+// matches e.g. context.Canceled.
+func (e *BatchError) Unwrap() error {
+	if indexes := e.indexes(); len(indexes) > 0 {
+		return e.Failed[indexes[0]]
+	}
+	return nil
+}
+
+func (e *BatchError) indexes() []int {
+	indexes := make([]int, 0, len(e.Failed))
+	for i := range e.Failed {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// SendBatch sends msgs to the named queue in batches of up to 10 messages and
+// 256 KB, returning their ids in the order of msgs. Entries failing because of
+// SQS rather than their content are sent again up to 3 times. The messages that
+// could not be sent are reported by a *BatchError, while the others are sent.
+//
+// On FIFO queues, the messages of a group are sent in order: the messages after
+// one sent again wait for it, and once one can't be sent, the later messages of
+// its group are not sent either and fail with ErrGroupFailed.
+func SendBatch(ctx context.Context, api SQSQueueAPI, name string, msgs []Message) ([]string, error) {
+	queueURL, err := URL(ctx, api, name)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(msgs))
+	sizes := make([]int, len(msgs))
+	invalid := make(map[int]error)
+	var groups []string
+	if IsFIFO(name) {
+		groups = make([]string, len(msgs))
+	}
+	for i, msg := range msgs {
+		sizes[i] = msg.size()
+		if err := msg.validate(name); err != nil {
+			invalid[i] = err
+		}
+		if groups != nil {
+			groups[i] = msg.GroupID
+		}
+	}
+
+	failed := runBatches(ctx, sizes, groups, invalid, func(ctx context.Context, chunk []int) (map[int]batchFailure, error) {
+		entries := make([]types.SendMessageBatchRequestEntry, 0, len(chunk))
+		for _, i := range chunk {
+			entries = append(entries, types.SendMessageBatchRequestEntry{
+				Id:                     aws.String(strconv.Itoa(i)),
+				MessageBody:            aws.String(msgs[i].Body),
+				DelaySeconds:           msgs[i].Delay,
+				MessageAttributes:      msgs[i].Attributes,
+				MessageGroupId:         optional(msgs[i].GroupID),
+				MessageDeduplicationId: optional(msgs[i].DeduplicationID),
+			})
+		}
+
+		result, err := api.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+			QueueUrl: aws.String(queueURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range result.Successful {
+			if i, err := strconv.Atoi(aws.ToString(s.Id)); err == nil {
+				ids[i] = aws.ToString(s.MessageId)
+			}
+		}
+		return batchFailures(result.Failed), nil
+	})
+	if len(failed) > 0 {
+		return ids, &BatchError{Op: "send", Failed: failed}
+	}
+	return ids, nil
+}
+
+// DeleteBatch deletes the messages with the given receipt handles from the named
+// queue in batches of up to 10, retrying like SendBatch. The messages that could
+// not be deleted are reported by a *BatchError.
+func DeleteBatch(ctx context.Context, api SQSQueueAPI, name string, receiptHandles []string) error {
+	queueURL, err := URL(ctx, api, name)
+	if err != nil {
+		return err
+	}
+
+	failed := runBatches(ctx, make([]int, len(receiptHandles)), nil, nil, func(ctx context.Context, chunk []int) (map[int]batchFailure, error) {
+		entries := make([]types.DeleteMessageBatchRequestEntry, 0, len(chunk))
+		for _, i := range chunk {
+			entries = append(entries, types.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(strconv.Itoa(i)),
+				ReceiptHandle: aws.String(receiptHandles[i]),
+			})
+		}
+
+		result, err := api.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+			QueueUrl: aws.String(queueURL),
+			Entries:  entries,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return batchFailures(result.Failed), nil
+	})
+	if len(failed) > 0 {
+		return &BatchError{Op: "delete", Failed: failed}
+	}
+	return nil
+}
+
+// size returns the size SQS accounts for msg: its body along with the
+// names, types and values of its attributes.
+func (msg Message) size() int {
+	n := len(msg.Body)
+	for name, attr := range msg.Attributes {
+		n += len(name) + len(aws.ToString(attr.DataType)) + len(aws.ToString(attr.StringValue)) + len(attr.BinaryValue)
+	}
+	return n
+}
+
+// batchFailure is the failure of an entry in a batch request.
+type batchFailure struct {
+	err   error
+	retry bool
+}
+
+// batchFailures indexes the failed entries of a batch response.
+func batchFailures(entries []types.BatchResultErrorEntry) map[int]batchFailure {
+	failed := make(map[int]batchFailure, len(entries))
+	for _, e := range entries {
+		i, err := strconv.Atoi(aws.ToString(e.Id))
+		if err != nil {
+			continue
+		}
+		failed[i] = batchFailure{
+			err:   fmt.Errorf("%s: %s", aws.ToString(e.Code), aws.ToString(e.Message)),
+			retry: !e.SenderFault,
+		}
+	}
+	return failed
+}
+
+// runBatches calls send with chunks of the indexes of entries with the given
+// sizes, sending again the entries that failed for a reason worth retrying.
+// Entries send does not report as failed are done, while invalid ones are not
+// sent at all. It returns the errors of the entries that could not be sent, by index.
+//
+// When groups is set, the entries of each non-empty group are kept in order: an
+// entry is only sent again when no later entry of its group was sent, the later
+// entries waiting for it, and once an entry of a group fails for good, the later
+// ones are not sent.
+func runBatches(ctx context.Context, sizes []int, groups []string, invalid map[int]error, send func(ctx context.Context, chunk []int) (map[int]batchFailure, error)) map[int]error {
+	group := func(i int) string {
+		if groups == nil {
+			return ""
+		}
+		return groups[i]
+	}
+
+	// stopped holds the first entry of each group that failed for good.
+	stopped := make(map[string]int)
+	stop := func(i int) {
+		if g := group(i); g != "" {
+			if _, ok := stopped[g]; !ok {
+				stopped[g] = i
+			}
+		}
+	}
+
+	failed := make(map[int]error)
+	pending := make([]int, 0, len(sizes))
+	for i, size := range sizes {
+		if j, ok := stopped[group(i)]; ok {
+			failed[i] = fmt.Errorf("%w: message %d", ErrGroupFailed, j)
+			continue
+		}
+		if err, ok := invalid[i]; ok {
+			failed[i] = err
+			stop(i)
+			continue
+		}
+		if size > maxBatchBytes {
+			failed[i] = ErrMessageTooLarge
+			stop(i)
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	backoff := batchBackoff
+	for attempt := 1; len(pending) > 0; attempt++ {
+		var retry []int
+		// held holds the first entry of each group to send again.
+		held := make(map[string]int)
+		for _, chunk := range chunkBatch(pending, sizes) {
+			var ready []int
+			for _, i := range chunk {
+				if j, ok := stopped[group(i)]; ok {
+					failed[i] = fmt.Errorf("%w: message %d", ErrGroupFailed, j)
+					continue
+				}
+				if j, ok := held[group(i)]; ok {
+					failed[i] = fmt.Errorf("%w: message %d", ErrGroupFailed, j)
+					retry = append(retry, i)
+					continue
+				}
+				ready = append(ready, i)
+			}
+			if len(ready) == 0 {
+				continue
+			}
+			chunk = ready
+
+			failures, err := send(ctx, chunk)
+			if err != nil {
+				// the whole request failed.
+				failures = make(map[int]batchFailure, len(chunk))
+				for _, i := range chunk {
+					failures[i] = batchFailure{err: err, retry: ctx.Err() == nil}
+				}
+			}
+			// last holds the last entry of each group sent by the request.
+			last := make(map[string]int)
+			for _, i := range chunk {
+				if _, ok := failures[i]; !ok {
+					last[group(i)] = i
+				}
+			}
+			for _, i := range chunk {
+				f, ok := failures[i]
+				if !ok {
+					delete(failed, i)
+					continue
+				}
+				failed[i] = f.err
+
+				g := group(i)
+				if g == "" {
+					if f.retry {
+						retry = append(retry, i)
+					}
+					continue
+				}
+				if _, ok := stopped[g]; ok {
+					continue
+				}
+				if k, ok := last[g]; !f.retry || (ok && k > i) {
+					stop(i)
+					continue
+				}
+				retry = append(retry, i)
+				if _, ok := held[g]; !ok {
+					held[g] = i
+				}
+			}
+		}
+
+		sort.Ints(retry)
+		pending = retry
+		if len(pending) == 0 || attempt == maxBatchAttempts {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			for _, i := range pending {
+				failed[i] = ctx.Err()
+			}
+			return failed
+		}
+	}
+	return failed
+}
+
+// chunkBatch splits indexes in batches of up to maxBatchEntries entries
+// whose sizes add up to at most maxBatchBytes.
+func chunkBatch(indexes []int, sizes []int) [][]int {
+	var chunks [][]int
+	var chunk []int
+	total := 0
+	for _, i := range indexes {
+		if len(chunk) == maxBatchEntries || total+sizes[i] > maxBatchBytes {
+			chunks = append(chunks, chunk)
+			chunk, total = nil, 0
+		}
+		chunk = append(chunk, i)
+		total += sizes[i]
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
@@ -0,0 +1,176 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// TestChunkBatch is the unit test to test chunkBatch function.
+func TestChunkBatch(t *testing.T) {
+	var tests = []struct {
+		sizes []int
+		want  string
+	}{
+		{sizes: nil, want: "[]"},
+		{sizes: make([]int, 25), want: "[[0 1 2 3 4 5 6 7 8 9] [10 11 12 13 14 15 16 17 18 19] [20 21 22 23 24]]"},
+		{sizes: []int{100 << 10, 100 << 10, 100 << 10, 10}, want: "[[0 1] [2 3]]"},
+		{sizes: []int{256 << 10, 1, 256 << 10}, want: "[[0] [1] [2]]"},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when testing %d entries", len(td.sizes))
+		t.Run(testname, func(t *testing.T) {
+			indexes := make([]int, len(td.sizes))
+			for i := range indexes {
+				indexes[i] = i
+			}
+			if got := fmt.Sprint(chunkBatch(indexes, td.sizes)); got != td.want {
+				t.Errorf("got %s, want %s", got, td.want)
+			}
+		})
+	}
+}
+
+// TestSendBatch is the unit test to test SendBatch and DeleteBatch functions.
+func TestSendBatch(t *testing.T) {
+	api := newFakeSQS()
+	if _, err := Create(ctx, api, "orders", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// "flaky" messages fail twice on the SQS side, "invalid" ones fail for good.
+	flaky := 0
+	api.fail = func(body string) *types.BatchResultErrorEntry {
+		switch {
+		case body == "flaky" && flaky < 2:
+			flaky++
+			return &types.BatchResultErrorEntry{Code: aws.String("InternalError"), Message: aws.String("try again")}
+		case body == "invalid":
+			return &types.BatchResultErrorEntry{Code: aws.String("InvalidMessageContents"), Message: aws.String("bad"), SenderFault: true}
+		}
+		return nil
+	}
+
+	var msgs []Message
+	for i := 0; i < 22; i++ {
+		msgs = append(msgs, Message{Body: fmt.Sprintf("order %d", i)})
+	}
+	msgs[3].Body = "flaky"
+	msgs[5].Body = "invalid"
+	msgs[8].Body = strings.Repeat("x", 257<<10)
+
+	ids, err := SendBatch(ctx, api, "orders", msgs)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("got %v, want a BatchError", err)
+	}
+	if len(batchErr.Failed) != 2 || batchErr.Failed[5] == nil || !errors.Is(batchErr.Failed[8], ErrMessageTooLarge) {
+		t.Errorf("got %v, want failures for messages 5 and 8", batchErr)
+	}
+	for i, id := range ids {
+		if (id == "") != (i == 5 || i == 8) {
+			t.Errorf("got id %q for message %d", id, i)
+		}
+	}
+	// the 21 messages small enough in 3 batches, then 2 retries of the flaky message alone.
+	if got := fmt.Sprint(api.batches); got != "[10 10 1 1 1]" {
+		t.Errorf("got batches of %s entries, want [10 10 1 1 1]", got)
+	}
+	if n := api.messages("orders"); n != 20 {
+		t.Errorf("got %d messages, want 20", n)
+	}
+
+	received, err := Receive(ctx, api, "orders", ReceiveOptions{MaxMessages: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handles := []string{"unknown"}
+	for _, msg := range received {
+		handles = append(handles, aws.ToString(msg.ReceiptHandle))
+	}
+	err = DeleteBatch(ctx, api, "orders", handles)
+	if !errors.As(err, &batchErr) || len(batchErr.Failed) != 1 || batchErr.Failed[0] == nil {
+		t.Errorf("got %v, want a failure for the unknown receipt handle", err)
+	}
+	if n := api.messages("orders"); n != 10 {
+		t.Errorf("got %d messages, want 10", n)
+	}
+}
+
+// TestSendBatchFIFO is the unit test to test SendBatch function keeps the groups of FIFO queues in order.
+func TestSendBatchFIFO(t *testing.T) {
+	api := newFakeSQS()
+	if _, err := Create(ctx, api, "orders.fifo", map[string]string{"ContentBasedDeduplication": "true"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// "flaky" messages fail once on the SQS side, "invalid" ones fail for good.
+	flaky := map[string]bool{}
+	api.fail = func(body string) *types.BatchResultErrorEntry {
+		switch {
+		case strings.HasPrefix(body, "flaky") && !flaky[body]:
+			flaky[body] = true
+			return &types.BatchResultErrorEntry{Code: aws.String("InternalError"), Message: aws.String("try again")}
+		case body == "invalid":
+			return &types.BatchResultErrorEntry{Code: aws.String("InvalidMessageContents"), Message: aws.String("bad"), SenderFault: true}
+		}
+		return nil
+	}
+
+	msgs := []Message{
+		{Body: "a1", GroupID: "a"},
+		{Body: "flaky a", GroupID: "a"}, // not sent again, a2 was sent
+		{Body: "a2", GroupID: "a"},
+		{Body: "b1", GroupID: "b"},
+		{Body: "invalid", GroupID: "b"},
+		{Body: "b2", GroupID: "b"}, // sent along with the invalid message
+		{Body: "c1", GroupID: "c"},
+		{Body: "flaky c", GroupID: "c"}, // sent again, along with c2
+		{Body: "e1", GroupID: "e"},
+		{Body: "e2", GroupID: "e"},
+		{Body: "a3", GroupID: "a"},
+		{Body: "b3", GroupID: "b"},
+		{Body: "c2", GroupID: "c"},
+		{Body: strings.Repeat("x", 257<<10), GroupID: "d"},
+		{Body: "d1", GroupID: "d"},
+		{Body: "e3", GroupID: "e"},
+	}
+	_, err := SendBatch(ctx, api, "orders.fifo", msgs)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("got %v, want a BatchError", err)
+	}
+	var failed []int
+	for i := range msgs {
+		if err := batchErr.Failed[i]; err != nil {
+			failed = append(failed, i)
+			if wantGroupErr := i == 10 || i == 11 || i == 14; errors.Is(err, ErrGroupFailed) != wantGroupErr {
+				t.Errorf("got error %v for message %d", err, i)
+			}
+		}
+	}
+	if got := fmt.Sprint(failed); got != "[1 4 10 11 13 14]" {
+		t.Errorf("got failures for messages %s, want [1 4 10 11 13 14]", got)
+	}
+	if got := fmt.Sprint(api.batches); got != "[10 1 2]" {
+		t.Errorf("got batches of %s entries, want [10 1 2]", got)
+	}
+
+	received, err := Receive(ctx, api, "orders.fifo", ReceiveOptions{MaxMessages: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]string)
+	for _, msg := range received {
+		group := msg.Attributes["MessageGroupId"]
+		got[group] = append(got[group], aws.ToString(msg.Body))
+	}
+	if want := "map[a:[a1 a2] b:[b1 b2] c:[c1 flaky c c2] e:[e1 e2 e3]]"; fmt.Sprint(got) != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+}
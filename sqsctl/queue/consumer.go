@@ -0,0 +1,197 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// Handler processes a message. The message is deleted when the handler returns
+// nil, and left in the queue to be received again once its visibility timeout
+// expires when it returns an error.
+type Handler func(ctx context.Context, msg types.Message) error
+
+// ConsumerOptions controls how Consume receives and handles messages.
+type ConsumerOptions struct {
+	Concurrency       int   // handlers running at once, 10 when unset
+	WaitTime          int32 // seconds to long poll for, 20 when unset
+	VisibilityTimeout int32 // seconds, the queue's setting when unset
+
+	// HeartbeatInterval, when set, is how often the visibility timeout of a message
+	// is extended while its handler runs, each time to twice the interval from then,
+	// and at least a second. It should be well under the visibility timeout.
+	HeartbeatInterval time.Duration
+
+	// MaxVisibility caps the time a message is kept from other consumers by the
+	// heartbeat, counted from its receipt. It is 12 hours, the SQS limit, when unset.
+	MaxVisibility time.Duration
+
+	// Offload, when set, hands handlers the offloaded bodies of messages, which
+	// are deleted from S3 along with their message.
+	Offload *Offload
+}
+
+const (
+	// maxReceive is the most messages a ReceiveMessage call returns.
+	maxReceive = 10
+
+	// receiveBackoff is how long to wait after a failed ReceiveMessage call.
+	receiveBackoff = time.Second
+
+	// deleteTimeout bounds the deletion of a handled message, which carries on
+	// after ctx is canceled so that handled messages are not processed twice.
+	deleteTimeout = 10 * time.Second
+)
+
+// Consume long-polls the named queue until ctx is canceled, running handler on
+// every message with up to opts.Concurrency handlers at a time. Messages are only
+// received when a handler is free to take them, so that they don't use up their
+// visibility timeout waiting. Once ctx is canceled, Consume stops receiving,
+// waits for the running handlers and returns nil.
+//
+// Handlers taking longer than the visibility timeout see their message delivered
+// again to another consumer, unless opts.HeartbeatInterval is set.
+//
+// Failures to receive messages are logged and retried, except for the queue
+// not existing, which is returned.
+func Consume(ctx context.Context, api SQSQueueAPI, name string, handler Handler, opts ConsumerOptions) error {
+	queueURL, err := URL(ctx, api, name)
+	if err != nil {
+		return err
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = maxReceive
+	}
+	waitTime := opts.WaitTime
+	if waitTime == 0 {
+		waitTime = 20
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// sem holds a token for every running handler.
+	sem := make(chan struct{}, concurrency)
+	for {
+		// wait for a free handler, then take as many as are free, up to maxReceive.
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil
+		}
+		free := 1
+	acquire:
+		for free < maxReceive {
+			select {
+			case sem <- struct{}{}:
+				free++
+			default:
+				break acquire
+			}
+		}
+
+		result, err := api.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(queueURL),
+			AttributeNames:        []types.QueueAttributeName{types.QueueAttributeNameAll},
+			MessageAttributeNames: []string{"All"},
+			MaxNumberOfMessages:   int32(free),
+			VisibilityTimeout:     opts.VisibilityTimeout,
+			WaitTimeSeconds:       waitTime,
+		})
+		received := time.Now()
+		var msgs []types.Message
+		if result != nil {
+			msgs = result.Messages
+		}
+
+		// give back the tokens of the handlers left without a message.
+		for i := len(msgs); i < free; i++ {
+			<-sem
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var notFound *types.QueueDoesNotExist
+			if errors.As(err, &notFound) {
+				return err
+			}
+			log.Printf("error receiving messages: %v", err)
+			select {
+			case <-time.After(receiveBackoff):
+			case <-ctx.Done():
+				return nil
+			}
+			continue
+		}
+
+		for _, msg := range msgs {
+			wg.Add(1)
+			go func(msg types.Message) {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				if err := handle(ctx, api, queueURL, handler, msg, received, opts); err != nil {
+					log.Printf("message %s: %v", aws.ToString(msg.MessageId), err)
+				}
+			}(msg)
+		}
+	}
+}
+
+// handle runs handler on msg and deletes it when the handler succeeds,
+// along with its offloaded body.
+func handle(ctx context.Context, api SQSQueueAPI, queueURL string, handler Handler, msg types.Message, received time.Time, opts ConsumerOptions) error {
+	loaded := msg
+	if opts.Offload != nil {
+		var err error
+		if loaded, err = opts.Offload.Load(ctx, msg); err != nil {
+			return fmt.Errorf("%w, leaving the message for redelivery", err)
+		}
+	}
+	if err := runHandler(ctx, api, queueURL, handler, loaded, received, opts); err != nil {
+		return fmt.Errorf("handler failed, leaving the message for redelivery: %w", err)
+	}
+
+	deleteCtx, cancel := context.WithTimeout(context.Background(), deleteTimeout)
+	defer cancel()
+
+	if _, err := api.DeleteMessage(deleteCtx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(queueURL),
+		ReceiptHandle: msg.ReceiptHandle,
+	}); err != nil {
+		return fmt.Errorf("error deleting the message: %w", err)
+	}
+	if opts.Offload != nil {
+		return opts.Offload.Release(deleteCtx, msg)
+	}
+	return nil
+}
+
+// runHandler runs handler on msg, along with its heartbeat when enabled,
+// turning panics into errors.
+func runHandler(ctx context.Context, api SQSQueueAPI, queueURL string, handler Handler, msg types.Message, received time.Time, opts ConsumerOptions) (err error) {
+	if opts.HeartbeatInterval > 0 {
+		ticker := time.NewTicker(opts.HeartbeatInterval)
+		defer ticker.Stop()
+		stop := heartbeat(api, queueURL, msg, received, ticker.C, opts.HeartbeatInterval, opts.MaxVisibility)
+		defer stop()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return handler(ctx, msg)
+}
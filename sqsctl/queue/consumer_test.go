@@ -0,0 +1,128 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// TestConsume is the unit test to test Consume function.
+func TestConsume(t *testing.T) {
+	api := newFakeSQS()
+	// failed messages are received again after a second.
+	if _, err := Create(ctx, api, "orders", map[string]string{"VisibilityTimeout": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if _, err := Send(ctx, api, "orders", Message{Body: fmt.Sprintf("order %d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		mu       sync.Mutex
+		handled  = make(map[string]int)
+		running  int32
+		maxSeen  int32
+		failOnce = "order 7"
+	)
+	handler := func(ctx context.Context, msg types.Message) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxSeen)
+			if n <= m || atomic.CompareAndSwapInt32(&maxSeen, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		body := aws.ToString(msg.Body)
+		handled[body]++
+		if body == failOnce && handled[body] == 1 {
+			return errors.New("transient failure")
+		}
+		return nil
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() {
+		done <- Consume(cctx, api, "orders", handler, ConsumerOptions{Concurrency: 3, WaitTime: 1})
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for api.messages("orders") > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	if n := api.messages("orders"); n != 0 {
+		t.Errorf("got %d messages left, want 0", n)
+	}
+	if len(handled) != 20 {
+		t.Errorf("got %d messages handled, want 20", len(handled))
+	}
+	if handled[failOnce] != 2 {
+		t.Errorf("got %q handled %d times, want 2", failOnce, handled[failOnce])
+	}
+	if maxSeen > 3 {
+		t.Errorf("got %d handlers running at once, want at most 3", maxSeen)
+	}
+}
+
+// TestConsumeStops is the unit test to test Consume waits for running handlers once canceled.
+func TestConsumeStops(t *testing.T) {
+	api := newFakeSQS()
+	if _, err := Create(ctx, api, "orders", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Send(ctx, api, "orders", Message{Body: "order 1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	started := make(chan struct{})
+	var finished int32
+	handler := func(ctx context.Context, msg types.Message) error {
+		close(started)
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		atomic.StoreInt32(&finished, 1)
+		return nil
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- Consume(cctx, api, "orders", handler, ConsumerOptions{WaitTime: 1})
+	}()
+	<-started
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Error("Consume returned before the running handler finished")
+	}
+	// the handler succeeded, so its message is deleted even though ctx was canceled.
+	if n := api.messages("orders"); n != 0 {
+		t.Errorf("got %d messages left, want 0", n)
+	}
+
+	if err := Consume(ctx, api, "missing", handler, ConsumerOptions{}); !errors.As(err, new(*types.QueueDoesNotExist)) {
+		t.Errorf("got %v, want QueueDoesNotExist", err)
+	}
+}
@@ -0,0 +1,266 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// redrivePolicy is the RedrivePolicy attribute of a queue with a dead-letter queue.
+type redrivePolicy struct {
+	DeadLetterTargetArn string `json:"deadLetterTargetArn"`
+	MaxReceiveCount     string `json:"maxReceiveCount"`
+}
+
+// CreateWithDLQ creates the named queue like Create, along with its dead-letter
+// queue dlq when it does not exist yet, and sets the redrive policy of the queue
+// so that messages received maxReceiveCount times are moved to dlq. It returns
+// the URLs of both queues.
+func CreateWithDLQ(ctx context.Context, api SQSQueueAPI, name string, attr map[string]string, dlq string, maxReceiveCount int) (string, string, error) {
+	if IsFIFO(name) != IsFIFO(dlq) {
+		return "", "", fmt.Errorf("%w: the dead-letter queue of a FIFO queue must be a FIFO queue, and conversely", ErrInvalidOption)
+	}
+	if maxReceiveCount < 1 || maxReceiveCount > 1000 {
+		return "", "", fmt.Errorf("%w: maxReceiveCount must be between 1 and 1000", ErrInvalidOption)
+	}
+
+	dlqURL, err := URL(ctx, api, dlq)
+	var notFound *types.QueueDoesNotExist
+	if errors.As(err, &notFound) {
+		dlqURL, err = Create(ctx, api, dlq, nil)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("dead-letter queue: %w", err)
+	}
+
+	arn, err := queueARN(ctx, api, dlqURL)
+	if err != nil {
+		return "", "", fmt.Errorf("dead-letter queue: %w", err)
+	}
+
+	queueURL, err := Create(ctx, api, name, attr)
+	if err != nil {
+		return "", "", err
+	}
+
+	// the policy is set separately, since the queue may exist with another one.
+	policy, err := json.Marshal(redrivePolicy{DeadLetterTargetArn: arn, MaxReceiveCount: strconv.Itoa(maxReceiveCount)})
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := api.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+		QueueUrl:   aws.String(queueURL),
+		Attributes: map[string]string{"RedrivePolicy": string(policy)},
+	}); err != nil {
+		return "", "", fmt.Errorf("error setting the redrive policy: %w", err)
+	}
+	return queueURL, dlqURL, nil
+}
+
+// queueARN returns the ARN of the queue at queueURL.
+func queueARN(ctx context.Context, api SQSQueueAPI, queueURL string) (string, error) {
+	result, err := api.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameQueueArn},
+	})
+	if err != nil {
+		return "", err
+	}
+	return result.Attributes[string(types.QueueAttributeNameQueueArn)], nil
+}
+
+// RedriveOptions controls how Redrive moves messages.
+type RedriveOptions struct {
+	Rate   float64 // messages moved per second, unlimited when 0
+	Max    int     // messages to move, all of them when 0
+	DryRun bool    // only list the messages that would be moved
+
+	// Visit, when set, is called with every message moved, or that would be moved.
+	Visit func(msg types.Message)
+}
+
+// redriveVisibility is how long, in seconds, messages are hidden from other
+// consumers of the dead-letter queue while Redrive handles them. It is a
+// variable so that tests can shorten it.
+var redriveVisibility int32 = 30
+
+// Redrive moves the messages of the dead-letter queue dlq back to the named
+// source queue, or to the only queue using dlq when source is empty, and returns
+// how many it moved. Messages keep their body and attributes, along with their
+// group on FIFO queues. Each message is deleted from dlq once it is sent, so
+// that an interrupted redrive can be resumed without losing any.
+//
+// With opts.Rate, Redrive receives no more messages at once than it can move
+// before their visibility timeout expires.
+//
+// With opts.DryRun, the messages are left in dlq and made visible again at the end.
+// The dry run stops once messages it already saw are received again, which happens
+// when dlq holds more messages than it can go through within the visibility timeout,
+// so its count is then a lower bound. On FIFO queues, the messages it holds also keep
+// back the other messages of their group, which it does not count.
+func Redrive(ctx context.Context, api SQSQueueAPI, dlq, source string, opts RedriveOptions) (int, error) {
+	dlqURL, err := URL(ctx, api, dlq)
+	if err != nil {
+		return 0, err
+	}
+	sourceURL, err := redriveSource(ctx, api, dlqURL, source)
+	if err != nil {
+		return 0, err
+	}
+
+	var tick <-chan time.Time
+	if opts.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	// wait waits for the turn of the next message to move.
+	wait := func() error {
+		if tick == nil {
+			return nil
+		}
+		select {
+		case <-tick:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	// limit the messages received at once to those moved within their visibility timeout.
+	batch := maxReceive
+	if opts.Rate > 0 && !opts.DryRun && opts.Rate*float64(redriveVisibility) < maxReceive {
+		batch = int(opts.Rate * float64(redriveVisibility))
+		if batch < 1 {
+			batch = 1
+		}
+	}
+
+	// with a dry run, received messages are released once all of them were seen.
+	var handles []string
+	seen := make(map[string]bool)
+	if opts.DryRun {
+		defer func() {
+			releaseCtx, cancel := context.WithTimeout(context.Background(), deleteTimeout)
+			defer cancel()
+			for _, handle := range handles {
+				api.ChangeMessageVisibility(releaseCtx, &sqs.ChangeMessageVisibilityInput{
+					QueueUrl:          aws.String(dlqURL),
+					ReceiptHandle:     aws.String(handle),
+					VisibilityTimeout: 0,
+				})
+			}
+		}()
+	}
+
+	moved := 0
+	for opts.Max == 0 || moved < opts.Max {
+		n := batch
+		if opts.Max > 0 && opts.Max-moved < n {
+			n = opts.Max - moved
+		}
+		// the turn of the first message is waited for before receiving it, so
+		// that it does not use up its visibility timeout.
+		if !opts.DryRun {
+			if err := wait(); err != nil {
+				return moved, err
+			}
+		}
+		result, err := api.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:              aws.String(dlqURL),
+			AttributeNames:        []types.QueueAttributeName{types.QueueAttributeNameAll},
+			MessageAttributeNames: []string{"All"},
+			MaxNumberOfMessages:   int32(n),
+			VisibilityTimeout:     redriveVisibility,
+			WaitTimeSeconds:       1,
+		})
+		if err != nil {
+			return moved, err
+		}
+		if len(result.Messages) == 0 {
+			return moved, nil
+		}
+
+		repeated := false
+		for i, msg := range result.Messages {
+			if opts.DryRun {
+				handles = append(handles, aws.ToString(msg.ReceiptHandle))
+				id := aws.ToString(msg.MessageId)
+				if seen[id] {
+					repeated = true
+					continue
+				}
+				seen[id] = true
+			} else {
+				if i > 0 {
+					if err := wait(); err != nil {
+						return moved, err
+					}
+				}
+				if err := redriveMessage(ctx, api, dlqURL, sourceURL, msg); err != nil {
+					return moved, fmt.Errorf("message %s: %w", aws.ToString(msg.MessageId), err)
+				}
+			}
+
+			moved++
+			if opts.Visit != nil {
+				opts.Visit(msg)
+			}
+		}
+		if repeated {
+			return moved, nil
+		}
+	}
+	return moved, nil
+}
+
+// redriveSource returns the URL of the named source queue, or of the only
+// queue whose dead-letter queue is at dlqURL when source is empty.
+func redriveSource(ctx context.Context, api SQSQueueAPI, dlqURL, source string) (string, error) {
+	if source != "" {
+		return URL(ctx, api, source)
+	}
+
+	result, err := api.ListDeadLetterSourceQueues(ctx, &sqs.ListDeadLetterSourceQueuesInput{QueueUrl: aws.String(dlqURL)})
+	if err != nil {
+		return "", err
+	}
+	if len(result.QueueUrls) != 1 {
+		return "", fmt.Errorf("%w: %d queues use %s as dead-letter queue, the source queue must be given", ErrInvalidOption, len(result.QueueUrls), dlqURL)
+	}
+	return result.QueueUrls[0], nil
+}
+
+// redriveMessage sends msg to the queue at sourceURL and deletes it from the
+// dead-letter queue at dlqURL.
+func redriveMessage(ctx context.Context, api SQSQueueAPI, dlqURL, sourceURL string, msg types.Message) error {
+	input := &sqs.SendMessageInput{
+		QueueUrl:          aws.String(sourceURL),
+		MessageBody:       msg.Body,
+		MessageAttributes: msg.MessageAttributes,
+	}
+	if group, ok := msg.Attributes[string(types.MessageSystemAttributeNameMessageGroupId)]; ok {
+		// the message id deduplicates the message when a redrive is resumed, while the
+		// original deduplication id could still drop it if the message was sent recently.
+		input.MessageGroupId = aws.String(group)
+		input.MessageDeduplicationId = msg.MessageId
+	}
+	if _, err := api.SendMessage(ctx, input); err != nil {
+		return fmt.Errorf("error sending the message: %w", err)
+	}
+
+	if _, err := api.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(dlqURL),
+		ReceiptHandle: msg.ReceiptHandle,
+	}); err != nil {
+		return fmt.Errorf("error deleting the message from the dead-letter queue: %w", err)
+	}
+	return nil
+}
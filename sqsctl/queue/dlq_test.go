@@ -0,0 +1,153 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// TestRedrive is the unit test to test CreateWithDLQ and Redrive functions.
+func TestRedrive(t *testing.T) {
+	api := newFakeSQS()
+
+	if _, _, err := CreateWithDLQ(ctx, api, "orders", nil, "orders-dlq.fifo", 2); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("got %v, want %v", err, ErrInvalidOption)
+	}
+
+	// messages are visible again right after being received.
+	_, _, err := CreateWithDLQ(ctx, api, "orders", map[string]string{"VisibilityTimeout": "0"}, "orders-dlq", 2)
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	attr, err := Attributes(ctx, api, "orders")
+	if err != nil || attr["RedrivePolicy"] != `{"deadLetterTargetArn":"arn:aws:sqs:us-west-2:000000000000:orders-dlq","maxReceiveCount":"2"}` {
+		t.Fatalf("got %v %v, want a redrive policy", attr["RedrivePolicy"], err)
+	}
+
+	blog := map[string]types.MessageAttributeValue{"Blog": {DataType: aws.String("String"), StringValue: aws.String("The Code Library")}}
+	for _, body := range []string{"order 1", "order 2", "order 3"} {
+		if _, err := Send(ctx, api, "orders", Message{Body: body, Attributes: blog}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the third receive moves the messages to the dead-letter queue.
+	for i := 0; i < 3; i++ {
+		if _, err := Receive(ctx, api, "orders", ReceiveOptions{MaxMessages: 10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, dlq := api.messages("orders"), api.messages("orders-dlq"); n != 0 || dlq != 3 {
+		t.Fatalf("got %d messages and %d dead letters, want 0 and 3", n, dlq)
+	}
+
+	var visited int
+	moved, err := Redrive(ctx, api, "orders-dlq", "", RedriveOptions{DryRun: true, Visit: func(types.Message) { visited++ }})
+	if err != nil || moved != 3 || visited != 3 {
+		t.Fatalf("got %d messages %v, want 3 to move", moved, err)
+	}
+	if dlq := api.messages("orders-dlq"); dlq != 3 {
+		t.Fatalf("got %d dead letters after a dry run, want 3", dlq)
+	}
+
+	moved, err = Redrive(ctx, api, "orders-dlq", "", RedriveOptions{Rate: 100, Max: 2})
+	if err != nil || moved != 2 {
+		t.Fatalf("got %d messages moved %v, want 2", moved, err)
+	}
+	moved, err = Redrive(ctx, api, "orders-dlq", "orders", RedriveOptions{Rate: 100})
+	if err != nil || moved != 1 {
+		t.Fatalf("got %d messages moved %v, want 1", moved, err)
+	}
+
+	msgs, err := Receive(ctx, api, "orders", ReceiveOptions{MaxMessages: 10})
+	if err != nil || len(msgs) != 3 {
+		t.Fatalf("got %d messages %v, want 3", len(msgs), err)
+	}
+	for _, msg := range msgs {
+		if got := aws.ToString(msg.MessageAttributes["Blog"].StringValue); got != "The Code Library" {
+			t.Errorf("got Blog attribute %q, want it preserved", got)
+		}
+	}
+	if dlq := api.messages("orders-dlq"); dlq != 0 {
+		t.Errorf("got %d dead letters, want 0", dlq)
+	}
+}
+
+// receiveCountingSQS records how many messages every ReceiveMessage call asks for.
+type receiveCountingSQS struct {
+	*fakeSQS
+
+	mu    sync.Mutex
+	asked []int32
+}
+
+func (r *receiveCountingSQS) ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	r.mu.Lock()
+	r.asked = append(r.asked, params.MaxNumberOfMessages)
+	r.mu.Unlock()
+	return r.fakeSQS.ReceiveMessage(ctx, params, optFns...)
+}
+
+// redriveFixture creates the orders queue along with its dead-letter queue, which
+// has the given attributes and holds n messages.
+func redriveFixture(t *testing.T, dlqAttr map[string]string, n int) *receiveCountingSQS {
+	api := &receiveCountingSQS{fakeSQS: newFakeSQS()}
+	if _, err := Create(ctx, api, "orders-dlq", dlqAttr); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := CreateWithDLQ(ctx, api, "orders", nil, "orders-dlq", 2); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if _, err := Send(ctx, api, "orders-dlq", Message{Body: fmt.Sprint("order ", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return api
+}
+
+// TestRedriveRate is the unit test to test Redrive function receives no more messages than it can move in time.
+func TestRedriveRate(t *testing.T) {
+	defer func(v int32) { redriveVisibility = v }(redriveVisibility)
+	redriveVisibility = 1
+
+	api := redriveFixture(t, nil, 7)
+	moved, err := Redrive(ctx, api, "orders-dlq", "", RedriveOptions{Rate: 5})
+	if err != nil || moved != 7 {
+		t.Fatalf("got %d messages moved %v, want 7", moved, err)
+	}
+	for _, n := range api.asked {
+		if n > 5 {
+			t.Errorf("got %d messages received at once, want at most 5 at 5 messages per second", n)
+		}
+	}
+	if n, dlq := api.messages("orders"), api.messages("orders-dlq"); n != 7 || dlq != 0 {
+		t.Errorf("got %d messages and %d dead letters, want 7 and 0", n, dlq)
+	}
+}
+
+// TestRedriveDryRunRepeats is the unit test to test Redrive function stops a dry run on messages seen before.
+func TestRedriveDryRunRepeats(t *testing.T) {
+	defer func(v int32) { redriveVisibility = v }(redriveVisibility)
+	// the messages are visible again right after being received, so that the
+	// dry run receives the first ones over and over.
+	redriveVisibility = 0
+
+	api := redriveFixture(t, map[string]string{"VisibilityTimeout": "0"}, 25)
+	ids := make(map[string]int)
+	moved, err := Redrive(ctx, api, "orders-dlq", "", RedriveOptions{DryRun: true, Visit: func(msg types.Message) {
+		ids[aws.ToString(msg.MessageId)]++
+	}})
+	if err != nil || moved != 10 || len(ids) != 10 {
+		t.Fatalf("got %d messages %v, %d distinct, want the 10 messages seen before the first repeat", moved, err, len(ids))
+	}
+	if dlq := api.messages("orders-dlq"); dlq != 25 {
+		t.Errorf("got %d dead letters after a dry run, want 25", dlq)
+	}
+}
@@ -0,0 +1,272 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// TypeAttribute is the message attribute holding the type of the messages sent by a Publisher.
+const TypeAttribute = "MessageType"
+
+// maxMessageAttributes is the most attributes a message may have.
+const maxMessageAttributes = 10
+
+// Publisher sends values of type T as JSON messages of type msgType. Struct fields
+// tagged with `sqs:"Name"` are also sent as the message attribute Name, whose data
+// type is String for strings, Number for integers and floats, and Binary for byte
+// slices, unless it is given after a comma, e.g. `sqs:"Article,String"`.
+type Publisher[T any] struct {
+	api     SQSQueueAPI
+	queue   string
+	msgType string
+	schemas *Registry
+}
+
+// NewPublisher returns a Publisher sending to the named queue. When schemas is not
+// nil, messages are validated against the schema of msgType before being sent.
+func NewPublisher[T any](api SQSQueueAPI, queue, msgType string, schemas *Registry) *Publisher[T] {
+	return &Publisher[T]{api: api, queue: queue, msgType: msgType, schemas: schemas}
+}
+
+// Message returns the message holding v, to set its FIFO options or send it with SendBatch.
+func (p *Publisher[T]) Message(v T) (Message, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return Message{}, err
+	}
+	if p.schemas != nil {
+		if err := p.schemas.Validate(p.msgType, body); err != nil {
+			return Message{}, err
+		}
+	}
+
+	attr, err := encodeAttributes(v)
+	if err != nil {
+		return Message{}, err
+	}
+	attr[TypeAttribute] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(p.msgType)}
+	if len(attr) > maxMessageAttributes {
+		return Message{}, fmt.Errorf("%w: %d message attributes, at most %d are allowed", ErrInvalidOption, len(attr), maxMessageAttributes)
+	}
+
+	return Message{Body: string(body), Attributes: attr}, nil
+}
+
+// Publish sends v, returning the id of its message.
+func (p *Publisher[T]) Publish(ctx context.Context, v T) (string, error) {
+	msg, err := p.Message(v)
+	if err != nil {
+		return "", err
+	}
+	return Send(ctx, p.api, p.queue, msg)
+}
+
+// Consumer receives values of type T sent by a Publisher[T].
+type Consumer[T any] struct {
+	api     SQSQueueAPI
+	queue   string
+	msgType string
+	schemas *Registry
+}
+
+// NewConsumer returns a Consumer receiving from the named queue. When schemas is
+// not nil, messages are validated against the schema of msgType before being decoded.
+func NewConsumer[T any](api SQSQueueAPI, queue, msgType string, schemas *Registry) *Consumer[T] {
+	return &Consumer[T]{api: api, queue: queue, msgType: msgType, schemas: schemas}
+}
+
+// Consume runs handler on the value of every message like Consume. Messages that
+// are not of the consumer's type or fail validation are not handed to handler and
+// are left in the queue, to end up in its dead-letter queue.
+func (c *Consumer[T]) Consume(ctx context.Context, handler func(ctx context.Context, v T, msg types.Message) error, opts ConsumerOptions) error {
+	return Consume(ctx, c.api, c.queue, func(ctx context.Context, msg types.Message) error {
+		v, err := c.Decode(msg)
+		if err != nil {
+			return err
+		}
+		return handler(ctx, v, msg)
+	}, opts)
+}
+
+// Decode validates msg and returns its value. The fields tagged with `sqs` are
+// set from the message attributes, which take precedence over the body.
+func (c *Consumer[T]) Decode(msg types.Message) (T, error) {
+	var v T
+	if got := aws.ToString(msg.MessageAttributes[TypeAttribute].StringValue); got != c.msgType {
+		return v, fmt.Errorf("%w: got message type %q, want %q", ErrInvalidMessage, got, c.msgType)
+	}
+
+	body := []byte(aws.ToString(msg.Body))
+	if c.schemas != nil {
+		if err := c.schemas.Validate(c.msgType, body); err != nil {
+			return v, err
+		}
+	}
+
+	if err := json.Unmarshal(body, &v); err != nil {
+		return v, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if err := decodeAttributes(msg.MessageAttributes, &v); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// attributeField is a struct field mapped to a message attribute.
+type attributeField struct {
+	index    int
+	name     string
+	dataType string
+}
+
+// attributeFields returns the fields of the struct type t tagged with `sqs`.
+func attributeFields(t reflect.Type) ([]attributeField, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	var fields []attributeField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("sqs")
+		if !ok || tag == "-" {
+			continue
+		}
+		if !f.IsExported() {
+			return nil, fmt.Errorf("%w: unexported field %s cannot be a message attribute", ErrInvalidOption, f.Name)
+		}
+
+		name, dataType := tag, ""
+		if j := strings.IndexByte(tag, ','); j >= 0 {
+			name, dataType = tag[:j], tag[j+1:]
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		kind := attributeKind(f.Type)
+		if kind == "" {
+			return nil, fmt.Errorf("%w: field %s of type %s cannot be a message attribute", ErrInvalidOption, f.Name, f.Type)
+		}
+		switch {
+		case dataType == "":
+			dataType = kind
+		case dataType == "String" && kind != "Binary", dataType == kind:
+		default:
+			return nil, fmt.Errorf("%w: field %s of type %s cannot be a %s message attribute", ErrInvalidOption, f.Name, f.Type, dataType)
+		}
+		fields = append(fields, attributeField{index: i, name: name, dataType: dataType})
+	}
+	return fields, nil
+}
+
+// attributeKind returns the default data type of attributes of type t, if any.
+func attributeKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "String"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "Number"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "Binary"
+		}
+	}
+	return ""
+}
+
+// encodeAttributes returns the message attributes of the tagged fields of v.
+func encodeAttributes(v interface{}) (map[string]types.MessageAttributeValue, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	attr := make(map[string]types.MessageAttributeValue)
+	if !rv.IsValid() {
+		return attr, nil
+	}
+
+	fields, err := attributeFields(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		fv := rv.Field(f.index)
+		value := types.MessageAttributeValue{DataType: aws.String(f.dataType)}
+		if f.dataType == "Binary" {
+			if fv.Len() == 0 {
+				continue // SQS rejects empty attribute values.
+			}
+			value.BinaryValue = fv.Bytes()
+		} else {
+			s := fmt.Sprint(fv.Interface())
+			if s == "" {
+				continue
+			}
+			value.StringValue = aws.String(s)
+		}
+		attr[f.name] = value
+	}
+	return attr, nil
+}
+
+// decodeAttributes sets the tagged fields of the struct v points to from attr.
+// When v points to a nil pointer to a struct, the struct is allocated.
+func decodeAttributes(attr map[string]types.MessageAttributeValue, v interface{}) error {
+	rv := reflect.ValueOf(v).Elem()
+	if rv.Kind() == reflect.Ptr {
+		if rv.Type().Elem().Kind() != reflect.Struct {
+			return nil
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+	fields, err := attributeFields(rv.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		a, ok := attr[f.name]
+		if !ok {
+			continue
+		}
+		fv := rv.Field(f.index)
+		s := aws.ToString(a.StringValue)
+
+		var err error
+		switch fv.Kind() {
+		case reflect.String:
+			fv.SetString(s)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			var n int64
+			if n, err = strconv.ParseInt(s, 10, fv.Type().Bits()); err == nil {
+				fv.SetInt(n)
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			var n uint64
+			if n, err = strconv.ParseUint(s, 10, fv.Type().Bits()); err == nil {
+				fv.SetUint(n)
+			}
+		case reflect.Float32, reflect.Float64:
+			var n float64
+			if n, err = strconv.ParseFloat(s, fv.Type().Bits()); err == nil {
+				fv.SetFloat(n)
+			}
+		case reflect.Slice:
+			fv.SetBytes(a.BinaryValue)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: attribute %s: %v", ErrInvalidMessage, f.name, err)
+		}
+	}
+	return nil
+}
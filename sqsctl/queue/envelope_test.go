@@ -0,0 +1,175 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+type order struct {
+	ID       string  `json:"id" sqs:"OrderId"`
+	Customer string  `json:"customer,omitempty" sqs:"Customer"`
+	Priority int     `json:"-" sqs:"Priority"`
+	Total    float64 `json:"total" sqs:"Total,String"`
+	Digest   []byte  `json:"-" sqs:"Digest"`
+	Items    []int   `json:"items"`
+}
+
+// TestPublisherMessage is the unit test to test Message function.
+func TestPublisherMessage(t *testing.T) {
+	schemas := NewRegistry()
+	if err := schemas.Register("order", []byte(orderSchema)); err != nil {
+		t.Fatal(err)
+	}
+	p := NewPublisher[order](nil, "orders", "order", schemas)
+
+	var tests = []struct {
+		order order
+		attr  map[string]string
+		err   error
+	}{
+		{
+			order: order{ID: "ord-1", Priority: 2, Total: 9.5, Digest: []byte{1}, Items: []int{1}},
+			attr:  map[string]string{"MessageType": "String=order", "OrderId": "String=ord-1", "Priority": "Number=2", "Total": "String=9.5", "Digest": "Binary=\x01"},
+		},
+		{
+			order: order{ID: "ord-2", Customer: "ada", Items: []int{}},
+			attr:  map[string]string{"MessageType": "String=order", "OrderId": "String=ord-2", "Customer": "String=ada", "Priority": "Number=0", "Total": "String=0"},
+		},
+		{order: order{ID: "2", Items: []int{}}, err: ErrInvalidMessage},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when publishing %+v", td.order)
+		t.Run(testname, func(t *testing.T) {
+			msg, err := p.Message(td.order)
+			if !errors.Is(err, td.err) {
+				t.Fatalf("got error %v, want %v", err, td.err)
+			}
+			if err != nil {
+				return
+			}
+			attr := make(map[string]string)
+			for name, a := range msg.Attributes {
+				attr[name] = aws.ToString(a.DataType) + "=" + aws.ToString(a.StringValue) + string(a.BinaryValue)
+			}
+			if !reflect.DeepEqual(attr, td.attr) {
+				t.Errorf("got attributes %v, want %v", attr, td.attr)
+			}
+		})
+	}
+
+	type tooMany struct {
+		A, B, C, D, E, F, G, H, I, J string `sqs:""`
+	}
+	if _, err := NewPublisher[tooMany](nil, "orders", "many", nil).Message(tooMany{A: "a", B: "b", C: "c", D: "d", E: "e", F: "f", G: "g", H: "h", I: "i", J: "j"}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("got %v, want %v", err, ErrInvalidOption)
+	}
+	type badTag struct {
+		Items []int `sqs:"Items"`
+	}
+	if _, err := NewPublisher[badTag](nil, "orders", "bad", nil).Message(badTag{}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("got %v, want %v", err, ErrInvalidOption)
+	}
+	type unexported struct {
+		secret string `sqs:"Secret"`
+	}
+	if _, err := NewPublisher[unexported](nil, "orders", "secret", nil).Message(unexported{secret: "s"}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("got %v, want %v", err, ErrInvalidOption)
+	}
+	msg := types.Message{Body: aws.String(`{}`), MessageAttributes: map[string]types.MessageAttributeValue{
+		TypeAttribute: {DataType: aws.String("String"), StringValue: aws.String("secret")},
+		"Secret":      {DataType: aws.String("String"), StringValue: aws.String("s")},
+	}}
+	if _, err := NewConsumer[unexported](nil, "orders", "secret", nil).Decode(msg); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("got %v, want %v", err, ErrInvalidOption)
+	}
+}
+
+// TestPointerDecode is the unit test to test Decode function for pointer types.
+func TestPointerDecode(t *testing.T) {
+	want := &order{ID: "ord-1", Priority: 3, Digest: []byte("abc"), Items: []int{1}}
+	msg, err := NewPublisher[*order](nil, "orders", "order", nil).Message(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewConsumer[*order](nil, "orders", "order", nil).Decode(types.Message{
+		Body:              aws.String(msg.Body),
+		MessageAttributes: msg.Attributes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+// TestTypedConsume is the unit test to test Publish and Consume functions of typed messages.
+func TestTypedConsume(t *testing.T) {
+	api := newFakeSQS()
+	if _, err := Create(ctx, api, "orders", map[string]string{"VisibilityTimeout": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	schemas := NewRegistry()
+	if err := schemas.Register("order", []byte(orderSchema)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []order{
+		{ID: "ord-1", Customer: "ada", Priority: 1, Total: 3, Digest: []byte("abc"), Items: []int{1, 2}},
+		{ID: "ord-2", Priority: -4, Total: 0.25, Items: []int{}},
+	}
+	p := NewPublisher[order](api, "orders", "order", schemas)
+	for _, o := range want {
+		if _, err := p.Publish(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// neither a message of another type nor one breaking the schema reaches the handler.
+	if _, err := Send(ctx, api, "orders", Message{Body: `{"id": "ord-3", "items": []}`}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Send(ctx, api, "orders", Message{Body: `{"id": "3"}`, Attributes: map[string]types.MessageAttributeValue{
+		TypeAttribute: {DataType: aws.String("String"), StringValue: aws.String("order")},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu  sync.Mutex
+		got = make(map[string]order)
+	)
+	c := NewConsumer[order](api, "orders", "order", schemas)
+	cctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+	err := c.Consume(cctx, func(ctx context.Context, o order, msg types.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got[o.ID] = o
+		return nil
+	}, ConsumerOptions{Concurrency: 2, WaitTime: 1})
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	for _, o := range want {
+		if !reflect.DeepEqual(got[o.ID], o) {
+			t.Errorf("got %+v, want %+v", got[o.ID], o)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d orders, want %d", len(got), len(want))
+	}
+	if n := api.messages("orders"); n != 2 {
+		t.Errorf("got %d messages left, want 2", n)
+	}
+}
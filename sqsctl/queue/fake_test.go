@@ -0,0 +1,472 @@
+package queue
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// fakeSQS is an in-memory SQSQueueAPI.
+type fakeSQS struct {
+	mu     sync.Mutex
+	queues map[string]*fakeQueue // by URL
+	nextID int
+
+	// fail, when set, makes batch entries fail with the entry it returns.
+	fail func(body string) *types.BatchResultErrorEntry
+	// batches holds the number of entries of every batch request.
+	batches []int
+}
+
+type fakeQueue struct {
+	name     string
+	attr     map[string]string
+	tags     map[string]string
+	messages []*fakeMessage
+	dedup    map[string]string // message ids by deduplication id, for FIFO queues
+}
+
+// fakeMessage is a message along with the time it becomes visible to receivers again.
+type fakeMessage struct {
+	msg       types.Message
+	group     string
+	visibleAt time.Time
+	receives  int
+}
+
+func newFakeSQS() *fakeSQS {
+	return &fakeSQS{queues: make(map[string]*fakeQueue)}
+}
+
+func (f *fakeSQS) queue(queueURL *string) (*fakeQueue, error) {
+	q, ok := f.queues[aws.ToString(queueURL)]
+	if !ok {
+		return nil, &types.QueueDoesNotExist{Message: aws.String("no queue " + aws.ToString(queueURL))}
+	}
+	return q, nil
+}
+
+func (f *fakeSQS) CreateQueue(ctx context.Context, params *sqs.CreateQueueInput, optFns ...func(*sqs.Options)) (*sqs.CreateQueueOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	queueURL := "http://sqs.test/000000000000/" + aws.ToString(params.QueueName)
+	if _, ok := f.queues[queueURL]; !ok {
+		attr := make(map[string]string)
+		for k, v := range params.Attributes {
+			attr[k] = v
+		}
+		f.queues[queueURL] = &fakeQueue{name: aws.ToString(params.QueueName), attr: attr, tags: params.Tags, dedup: make(map[string]string)}
+	}
+	return &sqs.CreateQueueOutput{QueueUrl: aws.String(queueURL)}, nil
+}
+
+func (f *fakeSQS) DeleteQueue(ctx context.Context, params *sqs.DeleteQueueInput, optFns ...func(*sqs.Options)) (*sqs.DeleteQueueOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := f.queue(params.QueueUrl); err != nil {
+		return nil, err
+	}
+	delete(f.queues, aws.ToString(params.QueueUrl))
+	return &sqs.DeleteQueueOutput{}, nil
+}
+
+func (f *fakeSQS) GetQueueUrl(ctx context.Context, params *sqs.GetQueueUrlInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for queueURL, q := range f.queues {
+		if q.name == aws.ToString(params.QueueName) {
+			return &sqs.GetQueueUrlOutput{QueueUrl: aws.String(queueURL)}, nil
+		}
+	}
+	return nil, &types.QueueDoesNotExist{Message: aws.String("no queue " + aws.ToString(params.QueueName))}
+}
+
+func (f *fakeSQS) ListQueues(ctx context.Context, params *sqs.ListQueuesInput, optFns ...func(*sqs.Options)) (*sqs.ListQueuesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var urls []string
+	for queueURL, q := range f.queues {
+		if strings.HasPrefix(q.name, aws.ToString(params.QueueNamePrefix)) {
+			urls = append(urls, queueURL)
+		}
+	}
+	sort.Strings(urls)
+
+	// like SQS, only paginate when MaxResults is set, the token being an index here.
+	if params.MaxResults == nil {
+		if len(urls) > 1000 {
+			urls = urls[:1000]
+		}
+		return &sqs.ListQueuesOutput{QueueUrls: urls}, nil
+	}
+	start := 0
+	if params.NextToken != nil {
+		fmt.Sscan(aws.ToString(params.NextToken), &start)
+	}
+	end := start + int(aws.ToInt32(params.MaxResults))
+	if end >= len(urls) {
+		return &sqs.ListQueuesOutput{QueueUrls: urls[start:]}, nil
+	}
+	return &sqs.ListQueuesOutput{QueueUrls: urls[start:end], NextToken: aws.String(fmt.Sprint(end))}, nil
+}
+
+func (f *fakeSQS) ListQueueTags(ctx context.Context, params *sqs.ListQueueTagsInput, optFns ...func(*sqs.Options)) (*sqs.ListQueueTagsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q, err := f.queue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	return &sqs.ListQueueTagsOutput{Tags: q.tags}, nil
+}
+
+func (f *fakeSQS) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q, err := f.queue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	// FIFO queues drop messages sent again with the same deduplication id.
+	dedup := aws.ToString(params.MessageDeduplicationId)
+	if q.attr["FifoQueue"] == "true" {
+		if params.MessageGroupId == nil {
+			return nil, errors.New("MissingParameter: MessageGroupId")
+		}
+		if dedup == "" && q.attr["ContentBasedDeduplication"] == "true" {
+			dedup = fmt.Sprintf("%x", sha256.Sum256([]byte(aws.ToString(params.MessageBody))))
+		}
+		if dedup == "" {
+			return nil, errors.New("InvalidParameterValue: MessageDeduplicationId")
+		}
+		if id, ok := q.dedup[dedup]; ok {
+			return &sqs.SendMessageOutput{MessageId: aws.String(id)}, nil
+		}
+	}
+
+	f.nextID++
+	id := fmt.Sprintf("msg-%d", f.nextID)
+	if dedup != "" {
+		q.dedup[dedup] = id
+	}
+	q.messages = append(q.messages, &fakeMessage{msg: types.Message{
+		MessageId:         aws.String(id),
+		Body:              params.MessageBody,
+		MessageAttributes: params.MessageAttributes,
+	}, group: aws.ToString(params.MessageGroupId)})
+	return &sqs.SendMessageOutput{MessageId: aws.String(id)}, nil
+}
+
+// SendMessageBatch enforces the limits of SQS on the number and size of entries.
+func (f *fakeSQS) SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	if err := f.batch(len(params.Entries)); err != nil {
+		return nil, err
+	}
+	size := 0
+	for _, e := range params.Entries {
+		size += len(aws.ToString(e.MessageBody))
+	}
+	if size > 256<<10 {
+		return nil, &types.BatchRequestTooLong{Message: aws.String("batch too long")}
+	}
+
+	out := &sqs.SendMessageBatchOutput{}
+	for _, e := range params.Entries {
+		if f.fail != nil {
+			if failure := f.fail(aws.ToString(e.MessageBody)); failure != nil {
+				failure.Id = e.Id
+				out.Failed = append(out.Failed, *failure)
+				continue
+			}
+		}
+		result, err := f.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:          params.QueueUrl,
+			MessageBody:       e.MessageBody,
+			DelaySeconds:      e.DelaySeconds,
+			MessageAttributes: e.MessageAttributes,
+
+			MessageGroupId:         e.MessageGroupId,
+			MessageDeduplicationId: e.MessageDeduplicationId,
+		})
+		if err != nil {
+			return nil, err
+		}
+		out.Successful = append(out.Successful, types.SendMessageBatchResultEntry{Id: e.Id, MessageId: result.MessageId})
+	}
+	return out, nil
+}
+
+func (f *fakeSQS) DeleteMessageBatch(ctx context.Context, params *sqs.DeleteMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	if err := f.batch(len(params.Entries)); err != nil {
+		return nil, err
+	}
+
+	out := &sqs.DeleteMessageBatchOutput{}
+	for _, e := range params.Entries {
+		_, err := f.DeleteMessage(ctx, &sqs.DeleteMessageInput{QueueUrl: params.QueueUrl, ReceiptHandle: e.ReceiptHandle})
+		if err != nil {
+			out.Failed = append(out.Failed, types.BatchResultErrorEntry{
+				Id:          e.Id,
+				Code:        aws.String("ReceiptHandleIsInvalid"),
+				Message:     aws.String(err.Error()),
+				SenderFault: true,
+			})
+			continue
+		}
+		out.Successful = append(out.Successful, types.DeleteMessageBatchResultEntry{Id: e.Id})
+	}
+	return out, nil
+}
+
+// batch records a batch request of n entries.
+func (f *fakeSQS) batch(n int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if n > 10 {
+		return &types.TooManyEntriesInBatchRequest{Message: aws.String("too many entries")}
+	}
+	f.batches = append(f.batches, n)
+	return nil
+}
+
+// ReceiveMessage hides the messages it returns for the visibility timeout,
+// and waits a little when there are none to mimic long polling.
+func (f *fakeSQS) ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	msgs, err := f.receive(params)
+	if err != nil || len(msgs) > 0 || params.WaitTimeSeconds == 0 {
+		return &sqs.ReceiveMessageOutput{Messages: msgs}, err
+	}
+
+	select {
+	case <-time.After(10 * time.Millisecond):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &sqs.ReceiveMessageOutput{}, nil
+}
+
+func (f *fakeSQS) receive(params *sqs.ReceiveMessageInput) ([]types.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q, err := f.queue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	n := int(params.MaxNumberOfMessages)
+	if n == 0 {
+		n = 1
+	}
+	visibility := params.VisibilityTimeout
+	if visibility == 0 {
+		visibility = 30
+		if v, ok := q.attr["VisibilityTimeout"]; ok {
+			fmt.Sscan(v, &visibility)
+		}
+	}
+
+	now := time.Now()
+	dlq, maxReceiveCount, hasDLQ := f.deadLetterQueue(q)
+	var msgs []types.Message
+	kept := q.messages[:0]
+	for _, m := range q.messages {
+		if len(msgs) == n || m.visibleAt.After(now) {
+			kept = append(kept, m)
+			continue
+		}
+		// like SQS, move messages received too many times to the dead-letter queue.
+		if hasDLQ && m.receives >= maxReceiveCount {
+			m.receives = 0
+			dlq.messages = append(dlq.messages, m)
+			continue
+		}
+		kept = append(kept, m)
+		f.nextID++
+		m.receives++
+		m.visibleAt = now.Add(time.Duration(visibility) * time.Second)
+		m.msg.ReceiptHandle = aws.String(fmt.Sprintf("receipt-%d", f.nextID))
+		m.msg.Attributes = map[string]string{"ApproximateReceiveCount": fmt.Sprint(m.receives)}
+		if m.group != "" {
+			m.msg.Attributes["MessageGroupId"] = m.group
+		}
+		msgs = append(msgs, m.msg)
+	}
+	q.messages = kept
+	return msgs, nil
+}
+
+func (f *fakeSQS) DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q, err := f.queue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range q.messages {
+		if aws.ToString(m.msg.ReceiptHandle) == aws.ToString(params.ReceiptHandle) {
+			q.messages = append(q.messages[:i], q.messages[i+1:]...)
+			return &sqs.DeleteMessageOutput{}, nil
+		}
+	}
+	return nil, &types.ReceiptHandleIsInvalid{Message: aws.String("no message " + aws.ToString(params.ReceiptHandle))}
+}
+
+func (f *fakeSQS) ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q, err := f.queue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range q.messages {
+		if aws.ToString(m.msg.ReceiptHandle) == aws.ToString(params.ReceiptHandle) {
+			m.visibleAt = time.Now().Add(time.Duration(params.VisibilityTimeout) * time.Second)
+			return &sqs.ChangeMessageVisibilityOutput{}, nil
+		}
+	}
+	return nil, &types.ReceiptHandleIsInvalid{Message: aws.String("no message " + aws.ToString(params.ReceiptHandle))}
+}
+
+// messages returns the number of messages left in the named queue.
+func (f *fakeSQS) messages(name string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, q := range f.queues {
+		if q.name == name {
+			return len(q.messages)
+		}
+	}
+	return 0
+}
+
+func (f *fakeSQS) PurgeQueue(ctx context.Context, params *sqs.PurgeQueueInput, optFns ...func(*sqs.Options)) (*sqs.PurgeQueueOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q, err := f.queue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	q.messages = nil
+	return &sqs.PurgeQueueOutput{}, nil
+}
+
+func (f *fakeSQS) GetQueueAttributes(ctx context.Context, params *sqs.GetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q, err := f.queue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	visible := 0
+	now := time.Now()
+	for _, m := range q.messages {
+		if !m.visibleAt.After(now) {
+			visible++
+		}
+	}
+	attr := map[string]string{
+		"ApproximateNumberOfMessages":           fmt.Sprint(visible),
+		"ApproximateNumberOfMessagesNotVisible": fmt.Sprint(len(q.messages) - visible),
+		"QueueArn":                              fakeARN(q.name),
+	}
+	for k, v := range q.attr {
+		attr[k] = v
+	}
+
+	// only return the attributes asked for, unless All is.
+	names := make(map[string]bool)
+	for _, name := range params.AttributeNames {
+		names[string(name)] = true
+	}
+	if !names[string(types.QueueAttributeNameAll)] {
+		for k := range attr {
+			if !names[k] {
+				delete(attr, k)
+			}
+		}
+	}
+	return &sqs.GetQueueAttributesOutput{Attributes: attr}, nil
+}
+
+func (f *fakeSQS) SetQueueAttributes(ctx context.Context, params *sqs.SetQueueAttributesInput, optFns ...func(*sqs.Options)) (*sqs.SetQueueAttributesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	q, err := f.queue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range params.Attributes {
+		q.attr[k] = v
+	}
+	return &sqs.SetQueueAttributesOutput{}, nil
+}
+
+func (f *fakeSQS) ListDeadLetterSourceQueues(ctx context.Context, params *sqs.ListDeadLetterSourceQueuesInput, optFns ...func(*sqs.Options)) (*sqs.ListDeadLetterSourceQueuesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	dlq, err := f.queue(params.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	var urls []string
+	for queueURL, q := range f.queues {
+		if policy, ok := f.redrivePolicy(q); ok && policy.DeadLetterTargetArn == fakeARN(dlq.name) {
+			urls = append(urls, queueURL)
+		}
+	}
+	return &sqs.ListDeadLetterSourceQueuesOutput{QueueUrls: urls}, nil
+}
+
+// redrivePolicy returns the redrive policy of q, if it has one.
+func (f *fakeSQS) redrivePolicy(q *fakeQueue) (redrivePolicy, bool) {
+	var policy redrivePolicy
+	if err := json.Unmarshal([]byte(q.attr["RedrivePolicy"]), &policy); err != nil {
+		return policy, false
+	}
+	return policy, true
+}
+
+// deadLetterQueue returns the dead-letter queue of q and the number of receives
+// after which messages are moved to it, if q has one.
+func (f *fakeSQS) deadLetterQueue(q *fakeQueue) (*fakeQueue, int, bool) {
+	policy, ok := f.redrivePolicy(q)
+	if !ok {
+		return nil, 0, false
+	}
+	maxReceiveCount, _ := strconv.Atoi(policy.MaxReceiveCount)
+	for _, dlq := range f.queues {
+		if fakeARN(dlq.name) == policy.DeadLetterTargetArn {
+			return dlq, maxReceiveCount, true
+		}
+	}
+	return nil, 0, false
+}
+
+func fakeARN(name string) string {
+	return "arn:aws:sqs:us-west-2:000000000000:" + name
+}
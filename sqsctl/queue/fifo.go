@@ -0,0 +1,65 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// fifoSuffix ends the name of every FIFO queue.
+const fifoSuffix = ".fifo"
+
+// maxFIFOIDLength is the longest a message group or deduplication id may be.
+const maxFIFOIDLength = 128
+
+// ErrInvalidOption is matched by the errors returned for queue attributes or
+// message options that SQS would reject, such as mixing FIFO and standard ones.
+var ErrInvalidOption = errors.New("invalid option")
+
+// IsFIFO reports whether the named queue is a FIFO queue.
+func IsFIFO(name string) bool {
+	return strings.HasSuffix(name, fifoSuffix)
+}
+
+// fifoAttributes checks the FIFO attributes of a queue to create, adding
+// FifoQueue for queues named like FIFO queues.
+func fifoAttributes(name string, attr map[string]string) (map[string]string, error) {
+	fifo := IsFIFO(name)
+	if v, ok := attr["FifoQueue"]; ok && (v == "true") != fifo {
+		return nil, fmt.Errorf("%w: FifoQueue=%s for queue %q, FIFO queue names end with %s", ErrInvalidOption, v, name, fifoSuffix)
+	}
+	if !fifo {
+		for _, a := range []string{"ContentBasedDeduplication", "DeduplicationScope", "FifoThroughputLimit"} {
+			if _, ok := attr[a]; ok {
+				return nil, fmt.Errorf("%w: %s is only supported by FIFO queues", ErrInvalidOption, a)
+			}
+		}
+		return attr, nil
+	}
+
+	withFIFO := map[string]string{"FifoQueue": "true"}
+	for k, v := range attr {
+		withFIFO[k] = v
+	}
+	return withFIFO, nil
+}
+
+// validate checks the options of msg are supported by the named queue.
+func (msg Message) validate(name string) error {
+	if !IsFIFO(name) {
+		if msg.GroupID != "" || msg.DeduplicationID != "" {
+			return fmt.Errorf("%w: message group and deduplication ids are only supported by FIFO queues", ErrInvalidOption)
+		}
+		return nil
+	}
+
+	switch {
+	case msg.GroupID == "":
+		return fmt.Errorf("%w: messages sent to FIFO queues need a message group id", ErrInvalidOption)
+	case msg.Delay != 0:
+		return fmt.Errorf("%w: FIFO queues only support a delay for the whole queue, not per message", ErrInvalidOption)
+	case len(msg.GroupID) > maxFIFOIDLength || len(msg.DeduplicationID) > maxFIFOIDLength:
+		return fmt.Errorf("%w: message group and deduplication ids are limited to %d characters", ErrInvalidOption, maxFIFOIDLength)
+	}
+	return nil
+}
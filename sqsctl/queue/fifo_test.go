@@ -0,0 +1,90 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// TestFIFOValidation is the unit test to test the options rejected for FIFO and standard queues.
+func TestFIFOValidation(t *testing.T) {
+	var tests = []struct {
+		queue string
+		msg   Message
+		ok    bool
+	}{
+		{queue: "orders", msg: Message{Body: "a", Delay: 10}, ok: true},
+		{queue: "orders", msg: Message{Body: "a", GroupID: "customer-1"}},
+		{queue: "orders", msg: Message{Body: "a", DeduplicationID: "a"}},
+		{queue: "orders.fifo", msg: Message{Body: "a", GroupID: "customer-1"}, ok: true},
+		{queue: "orders.fifo", msg: Message{Body: "a", GroupID: "customer-1", DeduplicationID: "a"}, ok: true},
+		{queue: "orders.fifo", msg: Message{Body: "a"}},
+		{queue: "orders.fifo", msg: Message{Body: "a", GroupID: "customer-1", Delay: 10}},
+		{queue: "orders.fifo", msg: Message{Body: "a", GroupID: string(make([]byte, 129))}},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when sending %+v to %s", td.msg, td.queue)
+		t.Run(testname, func(t *testing.T) {
+			err := td.msg.validate(td.queue)
+			if td.ok && err != nil {
+				t.Errorf("got error %v", err)
+			}
+			if !td.ok && !errors.Is(err, ErrInvalidOption) {
+				t.Errorf("got %v, want %v", err, ErrInvalidOption)
+			}
+		})
+	}
+
+	api := newFakeSQS()
+	if _, err := Create(ctx, api, "orders", map[string]string{"ContentBasedDeduplication": "true"}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("got %v, want %v", err, ErrInvalidOption)
+	}
+	if _, err := Create(ctx, api, "orders", map[string]string{"FifoQueue": "true"}); !errors.Is(err, ErrInvalidOption) {
+		t.Errorf("got %v, want %v", err, ErrInvalidOption)
+	}
+}
+
+// TestFIFODeduplication is the unit test to test messages sent to FIFO queues.
+func TestFIFODeduplication(t *testing.T) {
+	api := newFakeSQS()
+	if _, err := Create(ctx, api, "orders.fifo", map[string]string{"ContentBasedDeduplication": "true"}); err != nil {
+		t.Fatal(err)
+	}
+	attr, err := Attributes(ctx, api, "orders.fifo")
+	if err != nil || attr["FifoQueue"] != "true" {
+		t.Fatalf("got %v %v, want FifoQueue=true", attr, err)
+	}
+
+	msgs := []Message{
+		{Body: "order 1", GroupID: "customer-1"},
+		{Body: "order 1", GroupID: "customer-1"}, // dropped, same body
+		{Body: "order 2", GroupID: "customer-2", DeduplicationID: "order-2"},
+		{Body: "order 2 again", GroupID: "customer-2", DeduplicationID: "order-2"}, // dropped, same id
+		{Body: "order 3", GroupID: "customer-1"},
+	}
+	ids, err := SendBatch(ctx, api, "orders.fifo", msgs)
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if ids[0] != ids[1] || ids[2] != ids[3] {
+		t.Errorf("got ids %v, want duplicates to share their id", ids)
+	}
+	if _, err := Send(ctx, api, "orders.fifo", Message{Body: "order 4", GroupID: "customer-1"}); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	received, err := Receive(ctx, api, "orders.fifo", ReceiveOptions{MaxMessages: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, msg := range received {
+		got = append(got, aws.ToString(msg.Body)+"/"+msg.Attributes["MessageGroupId"])
+	}
+	if want := "[order 1/customer-1 order 2/customer-2 order 3/customer-1 order 4/customer-1]"; fmt.Sprint(got) != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+}
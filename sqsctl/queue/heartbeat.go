@@ -0,0 +1,80 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// maxVisibility is the longest SQS keeps a message invisible after receiving it.
+const maxVisibility = 12 * time.Hour
+
+// heartbeat extends the visibility timeout of msg on every tick of ticks, which
+// ticks every interval, to twice the interval and at least a second, until it is
+// stopped or ceiling has elapsed since the message was received. It returns a
+// function stopping it and waiting for it to exit.
+func heartbeat(api SQSQueueAPI, queueURL string, msg types.Message, received time.Time, ticks <-chan time.Time, interval, ceiling time.Duration) (stop func()) {
+	if ceiling <= 0 || ceiling > maxVisibility {
+		ceiling = maxVisibility
+	}
+
+	// the heartbeat outlives the consumer's context, since a handler still
+	// running after a cancellation must keep its message.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for {
+			var now time.Time
+			select {
+			case now = <-ticks:
+			case <-ctx.Done():
+				return
+			}
+
+			// SQS counts the visibility timeout in whole seconds from now.
+			extension := 2 * interval
+			if extension < time.Second {
+				extension = time.Second
+			}
+			if remaining := ceiling - now.Sub(received); remaining < extension {
+				extension = remaining
+			}
+			seconds := int32(extension / time.Second)
+			if seconds < 1 {
+				log.Printf("message %s: visibility timeout ceiling of %s reached", aws.ToString(msg.MessageId), ceiling)
+				return
+			}
+
+			_, err := api.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+				QueueUrl:          aws.String(queueURL),
+				ReceiptHandle:     msg.ReceiptHandle,
+				VisibilityTimeout: seconds,
+			})
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Printf("message %s: error extending the visibility timeout: %v", aws.ToString(msg.MessageId), err)
+
+				// the message is gone or was received by another consumer.
+				var invalid *types.ReceiptHandleIsInvalid
+				var notInflight *types.MessageNotInflight
+				if errors.As(err, &invalid) || errors.As(err, &notInflight) {
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
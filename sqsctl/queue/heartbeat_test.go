@@ -0,0 +1,74 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// visibilitySQS records the timeout of every ChangeMessageVisibility call.
+type visibilitySQS struct {
+	*fakeSQS
+
+	mu       sync.Mutex
+	timeouts []int32
+}
+
+func (v *visibilitySQS) ChangeMessageVisibility(ctx context.Context, params *sqs.ChangeMessageVisibilityInput, optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	v.mu.Lock()
+	v.timeouts = append(v.timeouts, params.VisibilityTimeout)
+	v.mu.Unlock()
+	return v.fakeSQS.ChangeMessageVisibility(ctx, params, optFns...)
+}
+
+// TestHeartbeat is the unit test to test heartbeat function.
+func TestHeartbeat(t *testing.T) {
+	var tests = []struct {
+		interval time.Duration
+		ceiling  time.Duration
+		ticks    []time.Duration // since the message was received
+		want     []int32
+	}{
+		{interval: 10 * time.Second, ticks: []time.Duration{10 * time.Second, 20 * time.Second}, want: []int32{20, 20}},
+		// sub-second intervals extend the timeout by the one second minimum.
+		{interval: 400 * time.Millisecond, ticks: []time.Duration{400 * time.Millisecond, 800 * time.Millisecond}, want: []int32{1, 1}},
+		// the last extension stops at the ceiling, and the heartbeat once it's reached.
+		{interval: 10 * time.Second, ceiling: 25 * time.Second, ticks: []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second}, want: []int32{15, 5}},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when testing an interval of %s and a ceiling of %s", td.interval, td.ceiling)
+		t.Run(testname, func(t *testing.T) {
+			api := &visibilitySQS{fakeSQS: newFakeSQS()}
+			queueURL, err := Create(ctx, api, "reports", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Send(ctx, api, "reports", Message{Body: "report 1"}); err != nil {
+				t.Fatal(err)
+			}
+			out, err := api.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{QueueUrl: aws.String(queueURL)})
+			if err != nil || len(out.Messages) != 1 {
+				t.Fatalf("got %v, %v receiving the message", out, err)
+			}
+
+			received := time.Now()
+			ticks := make(chan time.Time)
+			stop := heartbeat(api, queueURL, out.Messages[0], received, ticks, td.interval, td.ceiling)
+			for _, tick := range td.ticks {
+				ticks <- received.Add(tick)
+			}
+			stop()
+
+			if !reflect.DeepEqual(api.timeouts, td.want) {
+				t.Errorf("got visibility timeouts %v, want %v", api.timeouts, td.want)
+			}
+		})
+	}
+}
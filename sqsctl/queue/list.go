@@ -0,0 +1,153 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+const (
+	// maxListResults is the most queues a ListQueues call returns.
+	maxListResults = 1000
+
+	// listConcurrency is how many queues ListDetailed describes at once.
+	listConcurrency = 8
+)
+
+// ListOptions selects the queues listed by ListDetailed and what to return about them.
+type ListOptions struct {
+	Prefix string // of the names of the queues
+
+	// Tags selects the queues having all of these tags, with the same values.
+	Tags map[string]string
+
+	// WithTags returns the tags of every queue.
+	WithTags bool
+
+	// Attributes are the names of the attributes to return for every queue,
+	// such as ApproximateNumberOfMessages, or All.
+	Attributes []string
+}
+
+// QueueListing is a queue listed by ListDetailed.
+type QueueListing struct {
+	Name       string            `json:"name"`
+	URL        string            `json:"url"`
+	Tags       map[string]string `json:"tags,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// ListDetailed returns the queues selected by opts, in the order SQS lists them,
+// along with their tags and attributes when requested. Several queues are
+// described at once, and the first error stops the listing, except for queues
+// deleted since they were listed, which are left out.
+func ListDetailed(ctx context.Context, api SQSQueueAPI, opts ListOptions) ([]QueueListing, error) {
+	urls, err := List(ctx, api, opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	queues := make([]QueueListing, len(urls))
+	for i, queueURL := range urls {
+		queues[i] = QueueListing{Name: path.Base(queueURL), URL: queueURL}
+	}
+	if len(opts.Tags) == 0 && !opts.WithTags && len(opts.Attributes) == 0 {
+		return queues, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	gone := make([]bool, len(queues))
+	sem := make(chan struct{}, listConcurrency)
+	for i := range queues {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := describe(ctx, api, &queues[i], opts)
+			var notFound *types.QueueDoesNotExist
+			if errors.As(err, &notFound) {
+				gone[i] = true
+				return
+			}
+			if err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("%s: %w", queues[i].Name, err)
+					cancel()
+				})
+			}
+		}(i)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	// only keep the queues still there having the tags asked for.
+	kept := queues[:0]
+	for i, q := range queues {
+		if gone[i] || !hasTags(q.Tags, opts.Tags) {
+			continue
+		}
+		if !opts.WithTags {
+			q.Tags = nil
+		}
+		kept = append(kept, q)
+	}
+	return kept, nil
+}
+
+// describe fetches the tags and attributes of q asked for by opts.
+func describe(ctx context.Context, api SQSQueueAPI, q *QueueListing, opts ListOptions) error {
+	if len(opts.Tags) > 0 || opts.WithTags {
+		result, err := api.ListQueueTags(ctx, &sqs.ListQueueTagsInput{QueueUrl: aws.String(q.URL)})
+		if err != nil {
+			return err
+		}
+		q.Tags = result.Tags
+		// skip the attributes of the queues filtered out.
+		if !hasTags(q.Tags, opts.Tags) {
+			return nil
+		}
+	}
+
+	if len(opts.Attributes) > 0 {
+		names := make([]types.QueueAttributeName, len(opts.Attributes))
+		for i, name := range opts.Attributes {
+			names[i] = types.QueueAttributeName(name)
+		}
+		result, err := api.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+			QueueUrl:       aws.String(q.URL),
+			AttributeNames: names,
+		})
+		if err != nil {
+			return err
+		}
+		q.Attributes = result.Attributes
+	}
+	return nil
+}
+
+// hasTags reports whether tags holds every tag of want.
+func hasTags(tags, want map[string]string) bool {
+	for k, v := range want {
+		if got, ok := tags[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,156 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// TestListPagination is the unit test to test List function on more queues than fit in a page.
+func TestListPagination(t *testing.T) {
+	api := newFakeSQS()
+	for i := 0; i < 2500; i++ {
+		if _, err := Create(ctx, api, fmt.Sprintf("orders-%04d", i), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Create(ctx, api, "invoices", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	urls, err := List(ctx, api, "orders-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 2500 {
+		t.Fatalf("got %d queues, want 2500", len(urls))
+	}
+	seen := make(map[string]bool)
+	for _, u := range urls {
+		seen[u] = true
+	}
+	if len(seen) != 2500 {
+		t.Errorf("got %d distinct queues, want 2500", len(seen))
+	}
+}
+
+// TestListDetailed is the unit test to test ListDetailed function.
+func TestListDetailed(t *testing.T) {
+	api := newFakeSQS()
+	queues := map[string]map[string]string{
+		"orders":      {"team": "shop", "env": "prod"},
+		"orders-dlq":  {"team": "shop", "env": "prod"},
+		"orders-test": {"team": "shop", "env": "test"},
+		"invoices":    {"team": "billing", "env": "prod"},
+		"scratch":     nil,
+	}
+	for name, tags := range queues {
+		if _, err := api.CreateQueue(ctx, &sqs.CreateQueueInput{QueueName: aws.String(name), Tags: tags}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Send(ctx, api, "orders", Message{Body: "order 1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		opts ListOptions
+		want []QueueListing
+	}{
+		{
+			opts: ListOptions{Prefix: "orders-"},
+			want: []QueueListing{
+				{Name: "orders-dlq", URL: "http://sqs.test/000000000000/orders-dlq"},
+				{Name: "orders-test", URL: "http://sqs.test/000000000000/orders-test"},
+			},
+		},
+		{
+			opts: ListOptions{Tags: map[string]string{"env": "prod"}},
+			want: []QueueListing{
+				{Name: "invoices", URL: "http://sqs.test/000000000000/invoices"},
+				{Name: "orders", URL: "http://sqs.test/000000000000/orders"},
+				{Name: "orders-dlq", URL: "http://sqs.test/000000000000/orders-dlq"},
+			},
+		},
+		{
+			opts: ListOptions{Prefix: "orders", Tags: map[string]string{"env": "prod", "team": "shop"}, WithTags: true, Attributes: []string{"ApproximateNumberOfMessages"}},
+			want: []QueueListing{
+				{Name: "orders", URL: "http://sqs.test/000000000000/orders", Tags: queues["orders"], Attributes: map[string]string{"ApproximateNumberOfMessages": "1"}},
+				{Name: "orders-dlq", URL: "http://sqs.test/000000000000/orders-dlq", Tags: queues["orders-dlq"], Attributes: map[string]string{"ApproximateNumberOfMessages": "0"}},
+			},
+		},
+		{
+			opts: ListOptions{Prefix: "s", WithTags: true},
+			want: []QueueListing{
+				{Name: "scratch", URL: "http://sqs.test/000000000000/scratch"},
+			},
+		},
+		{
+			opts: ListOptions{Tags: map[string]string{"team": "ops"}},
+			want: []QueueListing{},
+		},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when listing with %+v", td.opts)
+		t.Run(testname, func(t *testing.T) {
+			got, err := ListDetailed(ctx, api, td.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, td.want) {
+				t.Errorf("got %+v, want %+v", got, td.want)
+			}
+		})
+	}
+}
+
+// deletingSQS deletes a queue right after listing the queues, as if it was
+// deleted while they were being described.
+type deletingSQS struct {
+	*fakeSQS
+	deleted string
+}
+
+func (d *deletingSQS) ListQueues(ctx context.Context, params *sqs.ListQueuesInput, optFns ...func(*sqs.Options)) (*sqs.ListQueuesOutput, error) {
+	out, err := d.fakeSQS.ListQueues(ctx, params, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := Delete(ctx, d.fakeSQS, d.deleted); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TestListDetailedDeleted is the unit test to test ListDetailed function leaves out the queues deleted while listing.
+func TestListDetailedDeleted(t *testing.T) {
+	var tests = []ListOptions{
+		{WithTags: true},
+		{Attributes: []string{"ApproximateNumberOfMessages"}},
+	}
+
+	for _, opts := range tests {
+		testname := fmt.Sprintf("when listing with %+v", opts)
+		t.Run(testname, func(t *testing.T) {
+			api := &deletingSQS{fakeSQS: newFakeSQS(), deleted: "orders-old"}
+			for _, name := range []string{"orders", "orders-old"} {
+				if _, err := Create(ctx, api, name, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := ListDetailed(ctx, api, opts)
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if len(got) != 1 || got[0].Name != "orders" {
+				t.Errorf("got %+v, want the orders queue alone", got)
+			}
+		})
+	}
+}
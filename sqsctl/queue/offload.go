@@ -0,0 +1,214 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+const (
+	// payloadSizeAttribute holds the size of an offloaded body. The extended
+	// client libraries of AWS set it, or legacyPayloadSizeAttribute for older ones.
+	payloadSizeAttribute       = "ExtendedPayloadSize"
+	legacyPayloadSizeAttribute = "SQSLargePayloadSize"
+
+	// pointerClass starts the body of messages pointing to an offloaded body.
+	pointerClass = "software.amazon.payloadoffloading.PayloadS3Pointer"
+)
+
+// S3API is the part of the S3 client used to offload message bodies,
+// so that tests can replace it.
+type S3API interface {
+	PutObject(ctx context.Context,
+		params *s3.PutObjectInput,
+		optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+
+	GetObject(ctx context.Context,
+		params *s3.GetObjectInput,
+		optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+
+	DeleteObject(ctx context.Context,
+		params *s3.DeleteObjectInput,
+		optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+// NewS3Client creates an s3 client. Buckets are addressed by path when
+// conf.Endpoint is set, as localstack requires.
+func NewS3Client(ctx context.Context, conf Config) (*s3.Client, error) {
+	cfg, err := loadConfig(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.UsePathStyle = conf.Endpoint != ""
+	}), nil
+}
+
+// Offload stores the bodies of messages too large for SQS in an S3 bucket and
+// sends a pointer to them instead, like the SQS extended client libraries of
+// AWS, which can read the messages it sends and the other way around.
+//
+// Objects are deleted by Release once their message is. Those of messages that
+// failed to send or expired are left behind, and are best removed by a
+// lifecycle rule of the bucket.
+type Offload struct {
+	API    S3API
+	Bucket string
+	Prefix string // of the object keys
+
+	// Threshold is the size above which a message is offloaded, counted like
+	// SQS does. It is 256 KB, the SQS limit, when unset.
+	Threshold int
+}
+
+// s3Pointer locates an offloaded body.
+type s3Pointer struct {
+	Bucket string `json:"s3BucketName"`
+	Key    string `json:"s3Key"`
+}
+
+// Store uploads the body of msg when it is larger than the threshold and returns
+// the message to send in its place, or returns msg unchanged. FIFO messages keep
+// the deduplication of their body, as their deduplication id defaults to its SHA-256.
+func (o *Offload) Store(ctx context.Context, msg Message) (Message, error) {
+	threshold := o.Threshold
+	if threshold <= 0 {
+		threshold = maxBatchBytes
+	}
+	if msg.size() <= threshold {
+		return msg, nil
+	}
+
+	key, err := objectKey(o.Prefix)
+	if err != nil {
+		return Message{}, err
+	}
+	if _, err := o.API.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(o.Bucket),
+		Key:           aws.String(key),
+		Body:          strings.NewReader(msg.Body),
+		ContentLength: int64(len(msg.Body)),
+	}); err != nil {
+		return Message{}, fmt.Errorf("error storing the message body: %w", err)
+	}
+
+	pointer, err := json.Marshal([]interface{}{pointerClass, s3Pointer{Bucket: o.Bucket, Key: key}})
+	if err != nil {
+		return Message{}, err
+	}
+	attr := make(map[string]types.MessageAttributeValue, len(msg.Attributes)+1)
+	for name, v := range msg.Attributes {
+		attr[name] = v
+	}
+	attr[payloadSizeAttribute] = types.MessageAttributeValue{
+		DataType:    aws.String("Number"),
+		StringValue: aws.String(strconv.Itoa(len(msg.Body))),
+	}
+
+	if msg.GroupID != "" && msg.DeduplicationID == "" {
+		sum := sha256.Sum256([]byte(msg.Body))
+		msg.DeduplicationID = hex.EncodeToString(sum[:])
+	}
+	msg.Body = string(pointer)
+	msg.Attributes = attr
+	return msg, nil
+}
+
+// Load returns msg with its offloaded body, if any, in place of the pointer.
+// The message received must be kept to Release the body once it is deleted.
+func (o *Offload) Load(ctx context.Context, msg types.Message) (types.Message, error) {
+	p, size, ok := parsePointer(msg)
+	if !ok {
+		return msg, nil
+	}
+
+	result, err := o.API.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(p.Bucket),
+		Key:    aws.String(p.Key),
+	})
+	if err != nil {
+		return msg, fmt.Errorf("error loading the message body from s3://%s/%s: %w", p.Bucket, p.Key, err)
+	}
+	defer result.Body.Close()
+
+	body, err := io.ReadAll(result.Body)
+	if err != nil {
+		return msg, fmt.Errorf("error loading the message body from s3://%s/%s: %w", p.Bucket, p.Key, err)
+	}
+	if len(body) != size {
+		return msg, fmt.Errorf("message body s3://%s/%s is %d bytes, want %d", p.Bucket, p.Key, len(body), size)
+	}
+
+	attr := make(map[string]types.MessageAttributeValue, len(msg.MessageAttributes))
+	for name, v := range msg.MessageAttributes {
+		if name != payloadSizeAttribute && name != legacyPayloadSizeAttribute {
+			attr[name] = v
+		}
+	}
+	msg.Body = aws.String(string(body))
+	msg.MessageAttributes = attr
+	return msg, nil
+}
+
+// Release deletes the offloaded body of msg, as received, if any.
+func (o *Offload) Release(ctx context.Context, msg types.Message) error {
+	p, _, ok := parsePointer(msg)
+	if !ok {
+		return nil
+	}
+
+	if _, err := o.API.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(p.Bucket),
+		Key:    aws.String(p.Key),
+	}); err != nil {
+		return fmt.Errorf("error deleting the message body s3://%s/%s: %w", p.Bucket, p.Key, err)
+	}
+	return nil
+}
+
+// parsePointer returns the location and size of the offloaded body of msg,
+// or false when its body was not offloaded.
+func parsePointer(msg types.Message) (s3Pointer, int, bool) {
+	attr, ok := msg.MessageAttributes[payloadSizeAttribute]
+	if !ok {
+		attr, ok = msg.MessageAttributes[legacyPayloadSizeAttribute]
+	}
+	if !ok {
+		return s3Pointer{}, 0, false
+	}
+	size, err := strconv.Atoi(aws.ToString(attr.StringValue))
+	if err != nil {
+		return s3Pointer{}, 0, false
+	}
+
+	var fields []json.RawMessage
+	if err := json.Unmarshal([]byte(aws.ToString(msg.Body)), &fields); err != nil || len(fields) != 2 {
+		return s3Pointer{}, 0, false
+	}
+	var class string
+	var p s3Pointer
+	if json.Unmarshal(fields[0], &class) != nil || class != pointerClass ||
+		json.Unmarshal(fields[1], &p) != nil || p.Bucket == "" || p.Key == "" {
+		return s3Pointer{}, 0, false
+	}
+	return p, size, true
+}
+
+// objectKey returns a new random key starting with prefix.
+func objectKey(prefix string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
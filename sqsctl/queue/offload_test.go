@@ -0,0 +1,217 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// fakeS3 is an in-memory S3 holding objects by bucket and key.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: make(map[string][]byte)}
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	b, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[aws.ToString(params.Bucket)+"/"+aws.ToString(params.Key)] = b
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, ok := f.objects[aws.ToString(params.Bucket)+"/"+aws.ToString(params.Key)]
+	if !ok {
+		return nil, errors.New("NoSuchKey")
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(b))}, nil
+}
+
+func (f *fakeS3) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.objects, aws.ToString(params.Bucket)+"/"+aws.ToString(params.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (f *fakeS3) len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.objects)
+}
+
+// TestOffloadStore is the unit test to test Store function.
+func TestOffloadStore(t *testing.T) {
+	var tests = []struct {
+		msg       Message
+		offloaded bool
+	}{
+		{msg: Message{Body: "small"}},
+		{msg: Message{Body: strings.Repeat("a", 100)}},
+		{msg: Message{Body: strings.Repeat("a", 101)}, offloaded: true},
+		{msg: Message{Body: strings.Repeat("a", 90), Attributes: map[string]types.MessageAttributeValue{
+			"Blog": {DataType: aws.String("String"), StringValue: aws.String("The Code Library")},
+		}}, offloaded: true},
+		{msg: Message{Body: strings.Repeat("b", 200), GroupID: "customer-1"}, offloaded: true},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when storing a message of %d bytes", td.msg.size())
+		t.Run(testname, func(t *testing.T) {
+			store := newFakeS3()
+			o := &Offload{API: store, Bucket: "payloads", Prefix: "orders/", Threshold: 100}
+			msg, err := o.Store(ctx, td.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !td.offloaded {
+				if msg.Body != td.msg.Body || store.len() != 0 {
+					t.Errorf("got body %q and %d objects, want the message unchanged", msg.Body, store.len())
+				}
+				return
+			}
+
+			if !strings.Contains(msg.Body, `"s3BucketName":"payloads","s3Key":"orders/`) || store.len() != 1 {
+				t.Fatalf("got body %q and %d objects, want a pointer to an object", msg.Body, store.len())
+			}
+			if size := aws.ToString(msg.Attributes[payloadSizeAttribute].StringValue); size != fmt.Sprint(len(td.msg.Body)) {
+				t.Errorf("got payload size %s, want %d", size, len(td.msg.Body))
+			}
+			if _, changed := td.msg.Attributes[payloadSizeAttribute]; changed || len(msg.Attributes) != len(td.msg.Attributes)+1 {
+				t.Errorf("got attributes %v, want a copy of those of the message along with the payload size", msg.Attributes)
+			}
+			if td.msg.GroupID != "" && len(msg.DeduplicationID) != 64 {
+				t.Errorf("got deduplication id %q, want the SHA-256 of the body", msg.DeduplicationID)
+			}
+
+			received := types.Message{Body: aws.String(msg.Body), MessageAttributes: msg.Attributes}
+			loaded, err := o.Load(ctx, received)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if aws.ToString(loaded.Body) != td.msg.Body || len(loaded.MessageAttributes) != len(td.msg.Attributes) {
+				t.Errorf("got %d bytes and attributes %v, want the message stored", len(aws.ToString(loaded.Body)), loaded.MessageAttributes)
+			}
+			if err := o.Release(ctx, received); err != nil || store.len() != 0 {
+				t.Errorf("got %v and %d objects, want the object deleted", err, store.len())
+			}
+		})
+	}
+}
+
+// TestOffloadLoad is the unit test to test Load function on messages sent by the extended client libraries.
+func TestOffloadLoad(t *testing.T) {
+	store := newFakeS3()
+	store.objects["payloads/1234"] = []byte("large body")
+	o := &Offload{API: store}
+
+	var tests = []struct {
+		body string
+		size string
+		want string
+		err  bool
+	}{
+		{body: `["software.amazon.payloadoffloading.PayloadS3Pointer",{"s3BucketName":"payloads","s3Key":"1234"}]`, size: "10", want: "large body"},
+		{body: `["software.amazon.payloadoffloading.PayloadS3Pointer",{"s3BucketName":"payloads","s3Key":"1234"}]`, size: "12", err: true},
+		{body: `["software.amazon.payloadoffloading.PayloadS3Pointer",{"s3BucketName":"payloads","s3Key":"5678"}]`, size: "10", err: true},
+		{body: `["a pointer", "to somewhere else"]`, size: "10", want: `["a pointer", "to somewhere else"]`},
+		{body: "small body", want: "small body"},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when loading %s of size %q", td.body, td.size)
+		t.Run(testname, func(t *testing.T) {
+			msg := types.Message{Body: aws.String(td.body)}
+			if td.size != "" {
+				msg.MessageAttributes = map[string]types.MessageAttributeValue{
+					legacyPayloadSizeAttribute: {DataType: aws.String("Number"), StringValue: aws.String(td.size)},
+				}
+			}
+			got, err := o.Load(ctx, msg)
+			if td.err {
+				if err == nil {
+					t.Errorf("got body %q, want an error", aws.ToString(got.Body))
+				}
+				return
+			}
+			if err != nil || aws.ToString(got.Body) != td.want {
+				t.Errorf("got %q %v, want %q", aws.ToString(got.Body), err, td.want)
+			}
+		})
+	}
+}
+
+// TestConsumeOffloaded is the unit test to test Consume function on offloaded messages.
+func TestConsumeOffloaded(t *testing.T) {
+	api := newFakeSQS()
+	if _, err := Create(ctx, api, "orders", nil); err != nil {
+		t.Fatal(err)
+	}
+	store := newFakeS3()
+	o := &Offload{API: store, Bucket: "payloads"}
+
+	bodies := map[string]bool{"small": true, strings.Repeat("a", 300<<10): true}
+	for body := range bodies {
+		msg, err := o.Store(ctx, Message{Body: body})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Send(ctx, api, "orders", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if store.len() != 1 {
+		t.Fatalf("got %d objects, want 1", store.len())
+	}
+
+	var (
+		mu  sync.Mutex
+		got = make(map[string]bool)
+	)
+	cctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+	err := Consume(cctx, api, "orders", func(ctx context.Context, msg types.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got[aws.ToString(msg.Body)] = true
+		return nil
+	}, ConsumerOptions{WaitTime: 1, Offload: o})
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	if len(got) != len(bodies) {
+		t.Errorf("got %d bodies, want %d", len(got), len(bodies))
+	}
+	for body := range bodies {
+		if !got[body] {
+			t.Errorf("body of %d bytes was not handled", len(body))
+		}
+	}
+	if n := store.len(); n != 0 {
+		t.Errorf("got %d objects left, want 0", n)
+	}
+	if n := api.messages("orders"); n != 0 {
+		t.Errorf("got %d messages left, want 0", n)
+	}
+}
@@ -0,0 +1,290 @@
+// Package queue wraps the SQS operations used by sqsctl.
+package queue
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// SQSQueueAPI is the part of the SQS client used by this package,
+// so that tests can replace it.
+type SQSQueueAPI interface {
+	CreateQueue(ctx context.Context,
+		params *sqs.CreateQueueInput,
+		optFns ...func(*sqs.Options)) (*sqs.CreateQueueOutput, error)
+
+	DeleteQueue(ctx context.Context,
+		params *sqs.DeleteQueueInput,
+		optFns ...func(*sqs.Options)) (*sqs.DeleteQueueOutput, error)
+
+	GetQueueUrl(ctx context.Context,
+		params *sqs.GetQueueUrlInput,
+		optFns ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error)
+
+	ListQueues(ctx context.Context,
+		params *sqs.ListQueuesInput,
+		optFns ...func(*sqs.Options)) (*sqs.ListQueuesOutput, error)
+
+	SendMessage(ctx context.Context,
+		params *sqs.SendMessageInput,
+		optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+
+	SendMessageBatch(ctx context.Context,
+		params *sqs.SendMessageBatchInput,
+		optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+
+	ReceiveMessage(ctx context.Context,
+		params *sqs.ReceiveMessageInput,
+		optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+
+	DeleteMessage(ctx context.Context,
+		params *sqs.DeleteMessageInput,
+		optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+
+	DeleteMessageBatch(ctx context.Context,
+		params *sqs.DeleteMessageBatchInput,
+		optFns ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error)
+
+	ChangeMessageVisibility(ctx context.Context,
+		params *sqs.ChangeMessageVisibilityInput,
+		optFns ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
+
+	PurgeQueue(ctx context.Context,
+		params *sqs.PurgeQueueInput,
+		optFns ...func(*sqs.Options)) (*sqs.PurgeQueueOutput, error)
+
+	GetQueueAttributes(ctx context.Context,
+		params *sqs.GetQueueAttributesInput,
+		optFns ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error)
+
+	SetQueueAttributes(ctx context.Context,
+		params *sqs.SetQueueAttributesInput,
+		optFns ...func(*sqs.Options)) (*sqs.SetQueueAttributesOutput, error)
+
+	ListDeadLetterSourceQueues(ctx context.Context,
+		params *sqs.ListDeadLetterSourceQueuesInput,
+		optFns ...func(*sqs.Options)) (*sqs.ListDeadLetterSourceQueuesOutput, error)
+
+	ListQueueTags(ctx context.Context,
+		params *sqs.ListQueueTagsInput,
+		optFns ...func(*sqs.Options)) (*sqs.ListQueueTagsOutput, error)
+}
+
+// Config selects the SQS endpoint and credentials to use. Empty fields
+// fall back to the default aws configuration.
+type Config struct {
+	Endpoint string // e.g. http://127.0.0.1:4566 for localstack
+	Region   string
+	Profile  string
+}
+
+// NewClient creates an sqs client.
+func NewClient(ctx context.Context, conf Config) (*sqs.Client, error) {
+	cfg, err := loadConfig(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	return sqs.NewFromConfig(cfg), nil
+}
+
+// loadConfig loads the default aws configuration, overridden by conf.
+func loadConfig(ctx context.Context, conf Config) (aws.Config, error) {
+	var opts []func(*config.LoadOptions) error
+	if conf.Endpoint != "" {
+		// customResolver is required when using localstack, to point the aws url to it.
+		customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+			return aws.Endpoint{
+				PartitionID:   "aws",
+				URL:           conf.Endpoint,
+				SigningRegion: region,
+			}, nil
+		})
+		opts = append(opts, config.WithEndpointResolverWithOptions(customResolver))
+	}
+	if conf.Region != "" {
+		opts = append(opts, config.WithRegion(conf.Region))
+	}
+	if conf.Profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(conf.Profile))
+	}
+
+	// load the default aws config along with the options.
+	return config.LoadDefaultConfig(ctx, opts...)
+}
+
+// URL returns the URL of the named queue.
+func URL(ctx context.Context, api SQSQueueAPI, name string) (string, error) {
+	result, err := api.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(name)})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(result.QueueUrl), nil
+}
+
+// Create creates a queue with the given name and attributes, returning its URL.
+// Queues named with a .fifo suffix are created as FIFO queues, and FIFO attributes
+// such as ContentBasedDeduplication are rejected for other queues, like the other
+// attributes SQS would reject.
+func Create(ctx context.Context, api SQSQueueAPI, name string, attr map[string]string) (string, error) {
+	attr, err := fifoAttributes(name, attr)
+	if err != nil {
+		return "", err
+	}
+	if err := validateAttributes(attr); err != nil {
+		return "", err
+	}
+
+	result, err := api.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName:  aws.String(name),
+		Attributes: attr,
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(result.QueueUrl), nil
+}
+
+// Delete deletes the named queue, returning the URL it had.
+func Delete(ctx context.Context, api SQSQueueAPI, name string) (string, error) {
+	queueURL, err := URL(ctx, api, name)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := api.DeleteQueue(ctx, &sqs.DeleteQueueInput{QueueUrl: aws.String(queueURL)}); err != nil {
+		return "", err
+	}
+	return queueURL, nil
+}
+
+// List returns the URLs of the queues whose name starts with prefix,
+// going through every page of results.
+func List(ctx context.Context, api SQSQueueAPI, prefix string) ([]string, error) {
+	input := &sqs.ListQueuesInput{
+		QueueNamePrefix: optional(prefix),
+		// SQS only paginates, past the first 1000 queues, when MaxResults is set.
+		MaxResults: aws.Int32(maxListResults),
+	}
+
+	var urls []string
+	pages := sqs.NewListQueuesPaginator(api, input)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, page.QueueUrls...)
+	}
+	return urls, nil
+}
+
+// Message is a message to send.
+type Message struct {
+	Body       string
+	Delay      int32 // seconds, not supported by FIFO queues
+	Attributes map[string]types.MessageAttributeValue
+
+	// GroupID orders the messages of a FIFO queue, which requires it.
+	GroupID string
+	// DeduplicationID identifies the message among those sent to a FIFO queue
+	// in the last 5 minutes. It is only optional for queues with content-based
+	// deduplication, which use the SHA-256 of the body instead.
+	DeduplicationID string
+}
+
+// Send sends a message to the named queue, returning its id.
+func Send(ctx context.Context, api SQSQueueAPI, name string, msg Message) (string, error) {
+	if err := msg.validate(name); err != nil {
+		return "", err
+	}
+	queueURL, err := URL(ctx, api, name)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := api.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:               aws.String(queueURL),
+		MessageBody:            aws.String(msg.Body),
+		DelaySeconds:           msg.Delay,
+		MessageAttributes:      msg.Attributes,
+		MessageGroupId:         optional(msg.GroupID),
+		MessageDeduplicationId: optional(msg.DeduplicationID),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(result.MessageId), nil
+}
+
+// ReceiveOptions controls how messages are received.
+type ReceiveOptions struct {
+	MaxMessages       int32 // 1 to 10
+	VisibilityTimeout int32 // seconds
+	WaitTime          int32 // seconds to long poll for, up to 20
+}
+
+// Receive receives messages from the named queue, along with their attributes.
+// The messages are not deleted: they reappear once their visibility timeout
+// expires, see Consume to process and delete them.
+func Receive(ctx context.Context, api SQSQueueAPI, name string, opts ReceiveOptions) ([]types.Message, error) {
+	queueURL, err := URL(ctx, api, name)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := api.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:              aws.String(queueURL),
+		MessageAttributeNames: []string{"All"},
+		MaxNumberOfMessages:   opts.MaxMessages,
+		VisibilityTimeout:     opts.VisibilityTimeout,
+		WaitTimeSeconds:       opts.WaitTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Messages, nil
+}
+
+// Purge deletes every message in the named queue.
+func Purge(ctx context.Context, api SQSQueueAPI, name string) error {
+	queueURL, err := URL(ctx, api, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = api.PurgeQueue(ctx, &sqs.PurgeQueueInput{QueueUrl: aws.String(queueURL)})
+	return err
+}
+
+// Attributes returns every attribute of the named queue.
+func Attributes(ctx context.Context, api SQSQueueAPI, name string) (map[string]string, error) {
+	queueURL, err := URL(ctx, api, name)
+	if err != nil {
+		return nil, err
+	}
+	return queueAttributes(ctx, api, queueURL)
+}
+
+// queueAttributes returns every attribute of the queue at queueURL.
+func queueAttributes(ctx context.Context, api SQSQueueAPI, queueURL string) (map[string]string, error) {
+	result, err := api.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameAll},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Attributes, nil
+}
+
+// optional returns a pointer to s, or nil when s is empty.
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
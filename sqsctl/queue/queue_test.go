@@ -0,0 +1,64 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+var ctx = context.Background()
+
+// TestQueueLifecycle is the unit test to test the queue operations one after another.
+func TestQueueLifecycle(t *testing.T) {
+	api := newFakeSQS()
+
+	queueURL, err := Create(ctx, api, "orders", map[string]string{"DelaySeconds": "60"})
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if _, err := Create(ctx, api, "invoices", nil); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	urls, err := List(ctx, api, "ord")
+	if err != nil || len(urls) != 1 || urls[0] != queueURL {
+		t.Fatalf("got %v %v, want [%s]", urls, err, queueURL)
+	}
+
+	id, err := Send(ctx, api, "orders", Message{Body: "order 1"})
+	if err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if _, err := Send(ctx, api, "orders", Message{Body: "order 2"}); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+
+	attr, err := Attributes(ctx, api, "orders")
+	if err != nil || attr["DelaySeconds"] != "60" || attr["ApproximateNumberOfMessages"] != "2" {
+		t.Fatalf("got %v %v, want DelaySeconds=60 and 2 messages", attr, err)
+	}
+
+	msgs, err := Receive(ctx, api, "orders", ReceiveOptions{MaxMessages: 1})
+	if err != nil || len(msgs) != 1 || aws.ToString(msgs[0].MessageId) != id {
+		t.Fatalf("got %v %v, want message %s", msgs, err, id)
+	}
+
+	if err := Purge(ctx, api, "orders"); err != nil {
+		t.Fatalf("got error %v", err)
+	}
+	if msgs, err := Receive(ctx, api, "orders", ReceiveOptions{MaxMessages: 10}); err != nil || len(msgs) != 0 {
+		t.Fatalf("got %v %v, want no messages", msgs, err)
+	}
+
+	if deleted, err := Delete(ctx, api, "orders"); err != nil || deleted != queueURL {
+		t.Fatalf("got %s %v, want %s", deleted, err, queueURL)
+	}
+
+	var notFound *types.QueueDoesNotExist
+	if _, err := Send(ctx, api, "orders", Message{Body: "order 3"}); !errors.As(err, &notFound) {
+		t.Errorf("got %v, want %T", err, notFound)
+	}
+}
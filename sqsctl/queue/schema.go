@@ -0,0 +1,341 @@
+package queue
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrInvalidMessage is matched by the errors returned for messages that do not
+// follow the schema of their type.
+var ErrInvalidMessage = errors.New("invalid message")
+
+// SchemaError reports where and why a message does not follow its schema.
+type SchemaError struct {
+	Path   string // JSON pointer to the offending value, e.g. /items/0/price
+	Reason string
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s: %s", pathOrRoot(e.Path), e.Reason)
+}
+
+// Unwrap returns ErrInvalidMessage.
+func (e *SchemaError) Unwrap() error {
+	return ErrInvalidMessage
+}
+
+// Schema is a JSON schema. The keywords supported are type, properties, required,
+// additionalProperties (as a boolean), items, enum, minimum, maximum, minLength,
+// maxLength and pattern, along with the annotations such as title and description.
+// ParseSchema rejects the others, so that no constraint of a schema goes unchecked.
+type Schema struct {
+	Type                 schemaTypes        `json:"type"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+
+	pattern *regexp.Regexp
+}
+
+// schemaTypes is the type keyword, either a type name or a list of them.
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = schemaTypes{name}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return errors.New("type must be a string or an array of strings")
+	}
+	*t = names
+	return nil
+}
+
+// schemaKeywords are the keywords ParseSchema accepts: those Schema checks,
+// and annotations, which do not constrain values.
+var schemaKeywords = map[string]bool{
+	"type": true, "properties": true, "required": true, "additionalProperties": true,
+	"items": true, "enum": true, "minimum": true, "maximum": true,
+	"minLength": true, "maxLength": true, "pattern": true,
+
+	"$schema": true, "$id": true, "$comment": true, "title": true,
+	"description": true, "default": true, "examples": true,
+}
+
+// ParseSchema parses a JSON schema, returning an error for the keywords Schema
+// does not support.
+func ParseSchema(data []byte) (*Schema, error) {
+	if err := checkKeywords("", data); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if err := s.compile(); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return &s, nil
+}
+
+// checkKeywords returns an error for the first unsupported keyword of the schema
+// data at path, or of its subschemas.
+func checkKeywords(path string, data []byte) error {
+	var keywords map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keywords); err != nil {
+		return fmt.Errorf("%s: a schema must be a JSON object", pathOrRoot(path))
+	}
+
+	names := make([]string, 0, len(keywords))
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !schemaKeywords[name] {
+			return fmt.Errorf("%s: unsupported keyword %q", pathOrRoot(path), name)
+		}
+	}
+
+	if items, ok := keywords["items"]; ok {
+		if err := checkKeywords(path+"/items", items); err != nil {
+			return err
+		}
+	}
+	if props, ok := keywords["properties"]; ok {
+		var properties map[string]json.RawMessage
+		if err := json.Unmarshal(props, &properties); err != nil {
+			return fmt.Errorf("%s/properties: must be a JSON object", path)
+		}
+		names := make([]string, 0, len(properties))
+		for name := range properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := checkKeywords(path+"/properties/"+escapePointer(name), properties[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pathOrRoot returns path, or / for the root.
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// compile checks the types and compiles the patterns of s and of its subschemas.
+func (s *Schema) compile() error {
+	for _, name := range s.Type {
+		switch name {
+		case "object", "array", "string", "number", "integer", "boolean", "null":
+		default:
+			return fmt.Errorf("unknown type %q", name)
+		}
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return err
+		}
+		s.pattern = re
+	}
+	for _, sub := range s.Properties {
+		if err := sub.compile(); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile()
+	}
+	return nil
+}
+
+// Validate checks the JSON document data follows s, returning a *SchemaError if not.
+func (s *Schema) Validate(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return &SchemaError{Reason: fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	return s.validate("", v)
+}
+
+func (s *Schema) validate(path string, v interface{}) error {
+	fail := func(format string, args ...interface{}) error {
+		return &SchemaError{Path: path, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	if len(s.Type) > 0 && !s.Type.match(v) {
+		return fail("got %s, want %s", jsonType(v), strings.Join(s.Type, " or "))
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		return fail("got %v, want one of %v", v, s.Enum)
+	}
+
+	switch v := v.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		if s.Minimum != nil && f < *s.Minimum {
+			return fail("got %v, want at least %v", v, *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			return fail("got %v, want at most %v", v, *s.Maximum)
+		}
+
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			return fail("got %d characters, want at least %d", n, *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return fail("got %d characters, want at most %d", n, *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return fail("got %q, want a match for %s", v, s.Pattern)
+		}
+
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				if err := s.Items.validate(fmt.Sprintf("%s/%d", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return fail("missing required property %q", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			sub, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fail("unexpected property %q", name)
+				}
+				continue
+			}
+			if err := sub.validate(path+"/"+escapePointer(name), v[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// match reports whether v has one of the types.
+func (t schemaTypes) match(v interface{}) bool {
+	for _, name := range t {
+		got := jsonType(v)
+		if got == name || (name == "number" && got == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType returns the JSON schema type of a decoded JSON value.
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// inEnum reports whether v is one of the values of enum, comparing their JSON encodings.
+func inEnum(enum []interface{}, v interface{}) bool {
+	got, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	for _, e := range enum {
+		if want, err := json.Marshal(e); err == nil && bytes.Equal(got, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
+
+// Registry holds the schemas of message types.
+type Registry struct {
+	mu      sync.RWMutex
+	schemas map[string]*Schema
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{schemas: make(map[string]*Schema)}
+}
+
+// Register sets the JSON schema of messages of type msgType.
+func (r *Registry) Register(msgType string, schema []byte) error {
+	s, err := ParseSchema(schema)
+	if err != nil {
+		return fmt.Errorf("%s: %w", msgType, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schemas[msgType] = s
+	return nil
+}
+
+// Validate checks body follows the schema of msgType.
+func (r *Registry) Validate(msgType string, body []byte) error {
+	r.mu.RLock()
+	s, ok := r.schemas[msgType]
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: no schema registered for message type %q", ErrInvalidMessage, msgType)
+	}
+	return s.Validate(body)
+}
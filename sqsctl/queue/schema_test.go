@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+const orderSchema = `{
+	"type": "object",
+	"required": ["id", "items"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "string", "pattern": "^ord-[0-9]+$"},
+		"customer": {"type": ["string", "null"], "minLength": 1, "maxLength": 8},
+		"status": {"enum": ["new", "paid"]},
+		"total": {"type": "number", "minimum": 0},
+		"items": {"type": "array", "items": {"type": "integer", "maximum": 100}}
+	}
+}`
+
+// TestSchemaValidate is the unit test to test Validate function.
+func TestSchemaValidate(t *testing.T) {
+	s, err := ParseSchema([]byte(orderSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		body string
+		ok   bool
+		path string
+	}{
+		{body: `{"id": "ord-1", "items": [1, 2]}`, ok: true},
+		{body: `{"id": "ord-1", "customer": null, "status": "paid", "total": 9.5, "items": []}`, ok: true},
+		{body: `{"id": "ord-1"}`, path: ""},
+		{body: `{"id": "order-1", "items": []}`, path: "/id"},
+		{body: `{"id": "ord-1", "items": [1.5]}`, path: "/items/0"},
+		{body: `{"id": "ord-1", "items": [1, 101]}`, path: "/items/1"},
+		{body: `{"id": "ord-1", "items": [], "total": -1}`, path: "/total"},
+		{body: `{"id": "ord-1", "items": [], "status": "lost"}`, path: "/status"},
+		{body: `{"id": "ord-1", "items": [], "customer": "someone-else"}`, path: "/customer"},
+		{body: `{"id": "ord-1", "items": [], "note": "a"}`, path: ""},
+		{body: `[]`, path: ""},
+		{body: `{`, path: ""},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when validating %s", td.body)
+		t.Run(testname, func(t *testing.T) {
+			err := s.Validate([]byte(td.body))
+			if td.ok {
+				if err != nil {
+					t.Errorf("got error %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidMessage) {
+				t.Fatalf("got %v, want %v", err, ErrInvalidMessage)
+			}
+			var schemaErr *SchemaError
+			if errors.As(err, &schemaErr) && schemaErr.Path != td.path {
+				t.Errorf("got path %q, want %q", schemaErr.Path, td.path)
+			}
+		})
+	}
+}
+
+// TestParseSchema is the unit test to test ParseSchema function.
+func TestParseSchema(t *testing.T) {
+	var tests = []struct {
+		schema string
+		ok     bool
+	}{
+		{schema: orderSchema, ok: true},
+		{schema: `{"$schema": "https://json-schema.org/draft/2020-12/schema", "title": "Order", "description": "an order", "type": "object"}`, ok: true},
+		{schema: `{"type": "object", "properties": {"items": {"type": "array"}}}`, ok: true},
+		{schema: `{"$ref": "#/$defs/order"}`},
+		{schema: `{"oneOf": [{"type": "string"}, {"type": "null"}]}`},
+		{schema: `{"type": "number", "exclusiveMinimum": 0}`},
+		{schema: `{"type": "array", "minItems": 1}`},
+		{schema: `{"type": "object", "properties": {"id": {"const": "ord-1"}}}`},
+		{schema: `{"type": "array", "items": {"type": "string", "format": "email"}}`},
+		{schema: `{"type": "object", "additionalProperties": {"type": "string"}}`},
+		{schema: `{"type": "thing"}`},
+		{schema: `[]`},
+	}
+
+	for _, td := range tests {
+		testname := fmt.Sprintf("when parsing %s", td.schema)
+		t.Run(testname, func(t *testing.T) {
+			_, err := ParseSchema([]byte(td.schema))
+			if td.ok && err != nil {
+				t.Errorf("got error %v", err)
+			}
+			if !td.ok && err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
+
+// TestRegistry is the unit test to test Registry type.
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register("order", []byte(`{"type": "thing"}`)); err == nil {
+		t.Error("registered a schema with an unknown type")
+	}
+	if err := r.Register("order", []byte(orderSchema)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Validate("order", []byte(`{"id": "ord-1", "items": []}`)); err != nil {
+		t.Errorf("got error %v", err)
+	}
+	if err := r.Validate("refund", []byte(`{}`)); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("got %v, want %v", err, ErrInvalidMessage)
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"log"
 	"strings"
 
@@ -45,10 +47,15 @@ func main() {
 func uploadFile(sess *session.Session, bucket, s3path, content string) error {
 	uploader := s3manager.NewUploader(sess)
 
+	// store the sha256 of the content as x-amz-meta-sha256, so the object can be
+	// verified later even when the ETag is not the md5 of the content.
+	sum := sha256.Sum256([]byte(content))
+
 	_, err := uploader.Upload(&s3manager.UploadInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(s3path),
-		Body:   strings.NewReader(content),
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(s3path),
+		Body:     strings.NewReader(content),
+		Metadata: map[string]*string{"sha256": aws.String(hex.EncodeToString(sum[:]))},
 	})
 
 	return err